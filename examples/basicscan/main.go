@@ -12,35 +12,31 @@ func main() {
 	//AddTargets增加目标，Addp增加端口范围，其他选项类似，如：-sV =》 AddsV()，-Pn => AddPn()
 	scanner := nmap.NewNmap().AddTargets("127.0.0.1").Addp("1-65535")
 
-	//Run运行
-	runResult := scanner.Run()
+	//Run运行，获取错误信息
+	runResult, err := scanner.Run()
+	if err != nil {
+		log.Fatal("error: ", err)
+	}
 
 	//获取警告信息
-	warn := runResult.WarnOut
+	warn := runResult.Warn
 	if warn != "" {
 		fmt.Printf("warn:\n%s", warn)
 	}
-	//获取错误信息
-	err := runResult.ErrOut
-	if err != nil {
-		log.Fatal("error: ", err)
-	}
-	//获取运行的xml结果
-	result := runResult.Result
 
-	//解析xml结果
-	parseResult := scanner.ParseXmlResult(result)
-	if err != nil {
-		log.Fatal(err)
-	}
-	xmlResult := parseResult.(*nmap.NmapXMLResult)
+	//获取解析后的xml结果，原始的xml结果为runResult.Raw
+	xmlResult := runResult.XML
 
 	//格式化输出xml结果
 	scanner.PrettyResult(xmlResult)
 
 	//导出xml结果到Excel
-	scanner.ExportResult(xmlResult)
+	if err := scanner.ExportResult(xmlResult); err != nil {
+		log.Fatal(err)
+	}
 
 	//导出xml结果到txt，用于导入魔方
-	scanner.ExportTxtResult(xmlResult)
+	if err := scanner.ExportTxtResult(xmlResult); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/er10yi/nmap-go/nmap"
+	"log"
+	"os"
+)
+
+// 对比两次扫描的nmap xml结果，输出新增/消失的host，开放/关闭的端口，服务版本、cpe、操作系统和脚本输出的变化
+func main() {
+
+	var before, after, format string
+	flag.StringVar(&before, "before", "", "旧的nmap xml结果")
+	flag.StringVar(&after, "after", "", "新的nmap xml结果")
+	flag.StringVar(&format, "format", "text", "输出格式，text、json或html")
+	flag.Parse()
+
+	if before == "" || after == "" {
+		fmt.Println("对比两次nmap xml结果")
+		fmt.Println("用法")
+		flag.PrintDefaults()
+		os.Exit(0)
+	}
+
+	beforeResult, _, err := nmap.RecoverXMLFile(before)
+	if err != nil {
+		log.Fatal(err)
+	}
+	afterResult, _, err := nmap.RecoverXMLFile(after)
+	if err != nil {
+		log.Fatal(err)
+	}
+	diff := nmap.Diff(beforeResult, afterResult)
+	switch format {
+	case "json":
+		err = diff.WriteJSON(os.Stdout)
+	case "html":
+		err = diff.WriteHTML(os.Stdout)
+	default:
+		err = diff.WriteText(os.Stdout)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	//有变化时退出码为1，便于告警
+	if !diff.Empty() {
+		os.Exit(1)
+	}
+}
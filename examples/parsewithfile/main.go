@@ -16,21 +16,27 @@ func main() {
 	//通过NewNmap()创建nmap ，并使用config配置
 	scanner := nmap.NewNmap(config)
 
-	//通过传入string的result，不需要run
+	//读取xml结果，不需要run
 	result, err := ioutil.ReadFile("examples/parsewithfile/nmap_example.xml")
+	if err != nil {
+		log.Fatal(err)
+	}
 	//解析xml结果
-	parseResult := scanner.ParseXmlResult(string(result))
+	xmlResult, err := nmap.ParseXML(result)
 	if err != nil {
 		log.Fatal(err)
 	}
-	xmlResult := parseResult.(*nmap.NmapXMLResult)
 
 	//格式化输出xml结果
 	scanner.PrettyResult(xmlResult)
 
 	//导出xml结果到Excel
-	scanner.ExportResult(xmlResult)
+	if err := scanner.ExportResult(xmlResult); err != nil {
+		log.Fatal(err)
+	}
 
 	//导出xml结果到txt，用于导入魔方
-	scanner.ExportTxtResult(xmlResult)
+	if err := scanner.ExportTxtResult(xmlResult); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -13,8 +13,8 @@ import (
 func main() {
 
 	var (
-		target, resultName                           string
-		show, showhost, showport, mergerow, addtable bool
+		target, resultName                                 string
+		show, showhost, showport, mergerow, addtable, html bool
 	)
 	flag.StringVar(&target, "target", "", "目标，待解析的nmap xml结果")
 	flag.StringVar(&resultName, "result", "Result", "结果，Excel文件名称")
@@ -23,6 +23,7 @@ func main() {
 	flag.BoolVar(&mergerow, "mergerow", true, "导出的Excel合并行")
 	flag.BoolVar(&addtable, "addtable", true, "导出的Excel增加table")
 	flag.BoolVar(&show, "show", true, "打印解析后的结果")
+	flag.BoolVar(&html, "html", false, "导出html报告")
 	flag.Parse()
 
 	if len(os.Args) == 1 {
@@ -50,13 +51,19 @@ func main() {
 	//通过NewNmap()创建nmap ，并使用config配置
 	scanner := nmap.NewNmap(config)
 
-	//通过传入string的result，解析xml结果
+	//读取并解析xml结果
 	result, err := ioutil.ReadFile(target)
-	parseResult := scanner.ParseXmlResult(string(result))
 	if err != nil {
 		log.Fatal(err)
 	}
-	xmlResult := parseResult.(*nmap.NmapXMLResult)
+	//容错解析，被终止的扫描产生的不完整xml也可以解析
+	xmlResult, recovery, err := nmap.RecoverXML(result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !recovery.Complete {
+		fmt.Printf("%s 不完整，%s\n", target, recovery)
+	}
 
 	//格式化输出xml结果
 	if show {
@@ -64,8 +71,19 @@ func main() {
 	}
 
 	//导出xml结果到Excel
-	scanner.ExportResult(xmlResult)
+	if err := scanner.ExportResult(xmlResult); err != nil {
+		log.Fatal(err)
+	}
+
+	//导出html报告
+	if html {
+		if err := scanner.ExportHTML(xmlResult); err != nil {
+			log.Fatal(err)
+		}
+	}
 
 	//导出xml结果到txt，用于导入魔方
-	scanner.ExportTxtResult(xmlResult)
+	if err := scanner.ExportTxtResult(xmlResult); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"github.com/er10yi/nmap-go/nmap"
+	"log"
+)
+
+// 两阶段扫描：先快速扫描常用端口，再只对开放的端口识别服务、运行默认脚本和识别操作系统
+func main() {
+	//发现扫描，指定目标
+	discovery := nmap.NewNmap().AddsS().Addtopports(100).AddTargets("192.168.1.0/24")
+	//深度扫描的模板，不需要指定目标和端口
+	deep := nmap.NewNmap().AddsV().AddsC().AddO()
+
+	pipeline := nmap.NewPipeline(discovery, deep)
+	//每个深度扫描最多16个host，同时运行4个
+	pipeline.BatchSize = 16
+	pipeline.Concurrency = 4
+
+	result, err := pipeline.Run()
+	if err != nil {
+		log.Fatal("error: ", err)
+	}
+	for _, scan := range result.Scans {
+		fmt.Println(scan.Targets, scan.Ports)
+	}
+
+	//合并后的结果
+	deep.PrettyResult(result.XML)
+	if err := deep.ExportResult(result.XML); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -17,28 +17,20 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	//Run运行
-	runResult := scanner.Run(ctx)
+	//Run运行，获取错误信息
+	runResult, err := scanner.Run(ctx)
+	if err != nil {
+		log.Fatal("error: ", err)
+	}
 
 	//获取警告信息
-	warn := runResult.WarnOut
+	warn := runResult.Warn
 	if warn != "" {
 		fmt.Printf("warn:\n%s", warn)
 	}
-	//获取错误信息
-	err := runResult.ErrOut
-	if err != nil {
-		log.Fatal("error: ", err)
-	}
-	//获取运行的xml结果
-	result := runResult.Result
 
-	//解析xml结果
-	parseResult := scanner.ParseXmlResult(result)
-	if err != nil {
-		log.Fatal(err)
-	}
-	xmlResult := parseResult.(*nmap.NmapXMLResult)
+	//获取解析后的xml结果
+	xmlResult := runResult.XML
 
 	//格式化输出xml结果
 	scanner.PrettyResult(xmlResult)
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/er10yi/nmap-go/nmap"
+	"github.com/pkg/errors"
 	"log"
 	"time"
 )
@@ -19,28 +20,26 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 	defer cancel()
 
-	//Run运行
-	runResult := scanner.Run(ctx)
+	//超时后先向nmap发送中断信号，10秒后仍未退出则强制结束
+	scanner.GracePeriod = 10 * time.Second
+
+	//Run运行，获取错误信息
+	runResult, err := scanner.Run(ctx)
+	//超时或取消时，runResult.XML中包含已完成的host
+	if errors.Is(err, nmap.ErrScanTimeout) {
+		fmt.Println("scan timeout, partial result:")
+	} else if err != nil {
+		log.Fatal("error: ", err)
+	}
 
 	//获取警告信息
-	warn := runResult.WarnOut
+	warn := runResult.Warn
 	if warn != "" {
 		fmt.Printf("warn:\n%s", warn)
 	}
-	//获取错误信息
-	err := runResult.ErrOut
-	if err != nil {
-		log.Fatal("error: ", err)
-	}
-	//获取运行的xml结果
-	result := runResult.Result
 
-	//解析xml结果
-	parseResult := scanner.ParseXmlResult(result)
-	if err != nil {
-		log.Fatal(err)
-	}
-	xmlResult := parseResult.(*nmap.NmapXMLResult)
+	//获取解析后的xml结果
+	xmlResult := runResult.XML
 
 	//格式化输出xml结果
 	scanner.PrettyResult(xmlResult)
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"github.com/er10yi/nmap-go/nmap"
+	"log"
+)
+
+// nmap 流式扫描，nmap运行过程中即可获取每个host的结果
+func main() {
+	//通过NewNmap()创建nmap
+	scanner := nmap.NewNmap().AddTargets("127.0.0.1").Addp("1-65535")
+
+	//RunStream运行，每个host扫描完成后立即回调
+	_, err := scanner.RunStream(func(event *nmap.StreamEvent) {
+		switch event.Type {
+		case nmap.EventHost:
+			for _, ports := range event.Host.Ports {
+				for _, port := range ports.Port {
+					fmt.Printf("%s %d/%s %s\n", event.Host.Address[0].Addr, port.PortId, port.Protocol, port.State.State)
+				}
+			}
+		case nmap.EventRunStats:
+			fmt.Println(event.RunStats.Finished.Summary)
+		}
+	})
+
+	//获取错误信息
+	if err != nil {
+		log.Fatal("error: ", err)
+	}
+}
@@ -5,6 +5,7 @@ go 1.18
 require (
 	github.com/pkg/errors v0.9.1
 	github.com/xuri/excelize/v2 v2.6.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -0,0 +1,217 @@
+package nmap
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// 旧版本nmap的参数和参数的别名，如NmapXMLResult.Args中记录的 nmap -sP 192.168.1.0/24
+var optionAliases = map[string]string{
+	"-sP":       "-sn",
+	"-P0":       "-Pn",
+	"-PN":       "-Pn",
+	"-PI":       "-PE",
+	"-sR":       "-sV",
+	"--fuzzy":   "--osscan-guess",
+	"--rH":      "--randomize-hosts",
+	"--proxy":   "--proxies",
+	"--version": "-V",
+	"--help":    "-h",
+}
+
+// ParseCommand 解析nmap命令行为nmap，如 nmap -sS -p 1-1000 -T4 --script "default and safe" 10.0.0.0/24
+//
+// 支持shell的引号和转义、合并的短参数（如-sSV、-nvT4）、--opt=value和单个-的长参数（如-script default），
+// 开头的nmap（或其路径）和sudo忽略；未知的参数返回ErrUnknownOption，缺少值的参数返回ErrInvalidOption，
+// 可用于重新运行NmapXMLResult.Args中记录的扫描，cfg为导出配置，与NewNmap相同
+func ParseCommand(command string, cfg ...*config) (*nmap, error) {
+	tokens, err := splitCommand(command)
+	if err != nil {
+		return nil, err
+	}
+	for len(tokens) != 0 && (tokens[0] == "sudo" || isNmapBin(tokens[0])) {
+		tokens = tokens[1:]
+	}
+	args, err := normalizeArgs(tokens)
+	if err != nil {
+		return nil, err
+	}
+	n := NewNmap(cfg...)
+	n.Args = args
+	return n, nil
+}
+
+func isNmapBin(token string) bool {
+	name := strings.ToLower(filepath.Base(filepath.ToSlash(token)))
+	return name == "nmap" || name == "nmap.exe"
+}
+
+// normalizeArgs 展开合并的短参数和单个-的长参数，检查未知的参数和缺少的值
+func normalizeArgs(tokens []string) ([]string, error) {
+	args, unknown := expandArgs(tokens)
+	if len(unknown) != 0 {
+		return nil, errors.Wrap(ErrUnknownOption, strings.Join(unknown, ", "))
+	}
+	options, _ := parseArgs(args)
+	var errs ValidationErrors
+	for _, option := range options {
+		if option.missing {
+			errs = append(errs, &ValidationError{Kind: ValidationRequires, Option: option.Name, Message: "requires a value"})
+		}
+	}
+	if len(errs) != 0 {
+		return nil, errs
+	}
+	return args, nil
+}
+
+// expandArgs 展开每个参数，未知的参数原样保留，同时返回未知的参数
+func expandArgs(tokens []string) ([]string, []string) {
+	var args, unknown []string
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		expanded, ok := expandOption(token)
+		if !ok {
+			unknown = append(unknown, token)
+			args = append(args, token)
+			continue
+		}
+		args = append(args, expanded...)
+		//带值的参数，值原样保留，即使以-开头（如-oX -）
+		last := expanded[len(expanded)-1]
+		if spec, ok := optionSpecs[last]; ok && spec.value && !spec.optional && i+1 < len(tokens) {
+			i++
+			args = append(args, tokens[i])
+		}
+	}
+	return args, unknown
+}
+
+// expandOption 将一个参数转换为已知的参数，目标原样返回，未知的参数返回false
+func expandOption(token string) ([]string, bool) {
+	if !strings.HasPrefix(token, "-") || token == "-" {
+		return []string{token}, true
+	}
+	name, _, hasValue := strings.Cut(token, "=")
+	if alias, ok := optionAliases[name]; ok {
+		if !hasValue {
+			return []string{alias}, true
+		}
+		if strings.HasPrefix(alias, "--") {
+			return []string{alias + token[len(name):]}, true
+		}
+	}
+	if isKnownOption(name) {
+		return []string{token}, true
+	}
+	//单个-的长参数，如-script、-top-ports=100
+	if !strings.HasPrefix(token, "--") && len(name) > 2 {
+		if spec, ok := optionSpecs["-"+name]; ok {
+			if hasValue {
+				if !spec.value {
+					return nil, false
+				}
+				return []string{"-" + token}, true
+			}
+			return []string{"-" + name}, true
+		}
+	}
+	if strings.HasPrefix(token, "--") {
+		return nil, false
+	}
+	//值紧跟参数，如-T4、-p1-1000、-PS22,80
+	for _, prefix := range attachedOptions {
+		if strings.HasPrefix(token, prefix) && len(token) > len(prefix) {
+			return []string{token}, true
+		}
+	}
+	//合并的扫描方式，如-sSV、-sUV
+	if strings.HasPrefix(token, "-s") {
+		var expanded []string
+		for _, c := range token[2:] {
+			option := "-s" + string(c)
+			if spec, ok := optionSpecs[option]; !ok || spec.value {
+				return nil, false
+			}
+			expanded = append(expanded, option)
+		}
+		return expanded, true
+	}
+	//合并的单字母参数，如-nvF，最后一个可以带值，如-nT4、-np 80
+	var expanded []string
+	for i := 1; i < len(token); i++ {
+		option := "-" + token[i:i+1]
+		spec, ok := optionSpecs[option]
+		if !ok && option != "-v" && option != "-d" {
+			return nil, false
+		}
+		if spec.value {
+			return append(expanded, option+token[i+1:]), true
+		}
+		expanded = append(expanded, option)
+	}
+	return expanded, true
+}
+
+func isKnownOption(name string) bool {
+	_, ok := optionSpecs[name]
+	return ok || levelRegexp.MatchString(name)
+}
+
+// splitCommand 按shell的规则分隔命令行，支持单引号、双引号和\转义
+func splitCommand(command string) ([]string, error) {
+	var tokens []string
+	var token strings.Builder
+	inToken := false
+	var quote rune
+	escaped := false
+	for _, c := range command {
+		switch {
+		case escaped:
+			//双引号中的\只转义 $ ` " \ ，其他字符保留\
+			if quote == '"' && !strings.ContainsRune("$`\"\\\n", c) {
+				token.WriteRune('\\')
+			}
+			if c != '\n' {
+				token.WriteRune(c)
+			}
+			escaped = false
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			} else {
+				token.WriteRune(c)
+			}
+		case c == '\\':
+			escaped = true
+			inToken = true
+		case quote == '"':
+			if c == '"' {
+				quote = 0
+			} else {
+				token.WriteRune(c)
+			}
+		case c == '\'' || c == '"':
+			quote = c
+			inToken = true
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inToken {
+				tokens = append(tokens, token.String())
+				token.Reset()
+				inToken = false
+			}
+		default:
+			token.WriteRune(c)
+			inToken = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, errors.Errorf("unterminated quote or escape in command %q", command)
+	}
+	if inToken {
+		tokens = append(tokens, token.String())
+	}
+	return tokens, nil
+}
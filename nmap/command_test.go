@@ -0,0 +1,125 @@
+package nmap
+
+import (
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestParseCommand(t *testing.T) {
+	cases := []struct {
+		command string
+		args    []string
+	}{
+		{`nmap -sS -p 1-1000 -T4 --script "default and safe" 10.0.0.0/24`,
+			[]string{"-sS", "-p", "1-1000", "-T4", "--script", "default and safe", "10.0.0.0/24"}},
+		{`sudo /usr/bin/nmap -sSV -nvT4 -Pn --top-ports=100 -oX - scanme.nmap.org`,
+			[]string{"-sS", "-sV", "-n", "-v", "-T4", "-Pn", "--top-ports=100", "-oX", "-", "scanme.nmap.org"}},
+		{`nmap -sP -PS22,80 -script http-title -script-args='http.useragent="a b"' -np 80 192.168.1.1`,
+			[]string{"-sn", "-PS22,80", "--script", "http-title", `--script-args=http.useragent="a b"`, "-n", "-p", "80", "192.168.1.1"}},
+		{`nmap -vv -d2 --exclude a\ b "C:\scan dir\targets.txt"`,
+			[]string{"-vv", "-d2", "--exclude", "a b", `C:\scan dir\targets.txt`}},
+	}
+	for _, c := range cases {
+		n, err := ParseCommand(c.command)
+		if err != nil {
+			t.Errorf("%s: %v", c.command, err)
+			continue
+		}
+		if !reflect.DeepEqual(n.Args, c.args) {
+			t.Errorf("%s: expected %q, but got %q", c.command, c.args, n.Args)
+		}
+	}
+
+	for _, command := range []string{"nmap --foo 127.0.0.1", "nmap -sSQ 127.0.0.1", "nmap -nk 127.0.0.1"} {
+		if _, err := ParseCommand(command); !errors.Is(err, ErrUnknownOption) {
+			t.Errorf("%s: expected ErrUnknownOption, but got %v", command, err)
+		}
+	}
+	if _, err := ParseCommand("nmap 127.0.0.1 -p"); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("expected missing value, but got %v", err)
+	}
+	if _, err := ParseCommand(`nmap --script "default`); err == nil {
+		t.Errorf("expected unterminated quote")
+	}
+
+	//重新运行xml结果中记录的扫描
+	result := loadXML(t, "testdata/scanme.xml")
+	n, err := ParseCommand(result.Args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec := n.Spec(); len(spec.Targets) == 0 {
+		t.Errorf("expected targets from %q", result.Args)
+	}
+}
+
+// helpOptionRegexp nmap -h中的参数，如 -PS/PA/PU/PY[portlist]、-f; --mtu <val>、--script=<Lua scripts>
+var helpOptionRegexp = regexp.MustCompile(`^  (-[^\s:;<\[=]+)(?:; (--[^\s:]+))?`)
+
+func TestParseCommandHelpOptions(t *testing.T) {
+	data, err := os.ReadFile("testdata/nmap-help.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	for _, line := range strings.Split(string(data), "\n") {
+		m := helpOptionRegexp.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		//-sS/sT/sA、--min-hostgroup/max-hostgroup、-n/-R
+		var options []string
+		prefix := "-"
+		if strings.HasPrefix(m[1], "--") {
+			prefix = "--"
+		}
+		for i, option := range strings.Split(m[1], "/") {
+			if i != 0 && !strings.HasPrefix(option, "-") {
+				option = prefix + option
+			}
+			options = append(options, option)
+		}
+		if m[2] != "" {
+			options = append(options, m[2])
+		}
+		for _, option := range options {
+			//带值的参数，如-T<0-5>、--script=<Lua scripts>、-p <port ranges>
+			command := "nmap " + option + " 10.0.0.1"
+			if spec, ok := optionSpecs[option]; ok && spec.value && !spec.optional {
+				command = "nmap " + option + " 1 10.0.0.1"
+			}
+			scanner, err := ParseCommand(command)
+			if err != nil {
+				t.Fatalf("%s: %v", command, err)
+			}
+			if targets := scanner.Spec().Targets; !reflect.DeepEqual(targets, []string{"10.0.0.1"}) {
+				t.Fatalf("%s: expected target 10.0.0.1, but got %v", command, targets)
+			}
+			count++
+		}
+	}
+	if count < 100 {
+		t.Fatalf("expected more than 100 options in nmap -h, but got %d", count)
+	}
+
+	//man page中的其他参数和别名
+	for command, args := range map[string][]string{
+		"nmap -sn -PR 192.168.1.0/24":               {"-sn", "-PR", "192.168.1.0/24"},
+		"nmap -sR -O --fuzzy --log-errors host":     {"-sV", "-O", "--osscan-guess", "--log-errors", "host"},
+		"nmap --iflist":                             {"--iflist"},
+		"nmap --proxy=socks4://127.0.0.1:1080 host": {"--proxies=socks4://127.0.0.1:1080", "host"},
+	} {
+		scanner, err := ParseCommand(command)
+		if err != nil {
+			t.Fatalf("%s: %v", command, err)
+		}
+		if !reflect.DeepEqual(scanner.Args, args) {
+			t.Fatalf("%s: expected %q, but got %q", command, args, scanner.Args)
+		}
+	}
+}
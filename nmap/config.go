@@ -4,15 +4,27 @@ type config struct {
 	//结果文件的名称
 	ResultName string `json:"result_name"`
 	//打印的结果中显示Hosthint
-	ShowHosthint bool
+	ShowHosthint bool `json:"show_hosthint"`
 	//打印的结果中显示host 和 port 信息
-	ShowHostPort bool
+	ShowHostPort bool `json:"show_host_port"`
 	//导出的Excel结果中合并行
-	MergeRow bool
+	MergeRow bool `json:"merge_row"`
 	//导出的Excel结果中增加表格
-	AddTable bool
+	AddTable bool `json:"add_table"`
 	//保存xml结果
 	SaveXmlRaw bool `json:"save_xml_raw"`
+	//导出的Excel结果中增加概况表，包括扫描参数、host数、端口状态饼图和常见服务，以下增加的表默认不开启
+	SummarySheet bool `json:"summary_sheet"`
+	//导出的Excel结果中增加操作系统表
+	OSSheet bool `json:"os_sheet"`
+	//导出的Excel结果中增加脚本表，包括prescript、hostscript和postscript
+	ScriptSheet bool `json:"script_sheet"`
+	//导出的Excel结果中增加traceroute表
+	TraceSheet bool `json:"trace_sheet"`
+	//导出的Excel结果中增加服务透视表，每个服务一行，每个host一列
+	ServiceSheet bool `json:"service_sheet"`
+	//导出txt使用的内置模板名称（TextMofang、TextGrepable、TextHostPort、TextMasscan）或text/template模板内容，为空时为魔方格式
+	TxtTemplate string `json:"txt_template"`
 }
 
 func NewConfig() *config {
@@ -0,0 +1,322 @@
+package nmap
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ChangeType 两次扫描之间的变化类型
+type ChangeType string
+
+const (
+	ChangeAdded   ChangeType = "added"
+	ChangeRemoved ChangeType = "removed"
+	ChangeChanged ChangeType = "changed"
+	//端口从未开放变为开放
+	ChangeOpened ChangeType = "opened"
+	//端口从开放变为未开放
+	ChangeClosed ChangeType = "closed"
+)
+
+// ScanDiff 两次扫描结果的差异，按地址对比host
+type ScanDiff struct {
+	Before ScanRef    `json:"before"`
+	After  ScanRef    `json:"after"`
+	Hosts  []HostDiff `json:"hosts"`
+}
+
+// ScanRef 参与对比的扫描
+type ScanRef struct {
+	Args     string `json:"args"`
+	Start    int64  `json:"start"`
+	StartStr string `json:"startstr"`
+}
+
+// HostDiff host的变化，host新增或消失时Ports包含其所有开放端口
+type HostDiff struct {
+	//ip地址，没有ip时为mac地址
+	Address   string       `json:"address"`
+	Hostnames []string     `json:"hostnames,omitempty"`
+	Change    ChangeType   `json:"change"`
+	State     *ValueChange `json:"state,omitempty"`
+	//准确率最高的操作系统识别结果
+	OS      *ValueChange `json:"os,omitempty"`
+	CPE     *SetChange   `json:"cpe,omitempty"`
+	Ports   []PortDiff   `json:"ports,omitempty"`
+	Scripts []ScriptDiff `json:"scripts,omitempty"`
+}
+
+// PortDiff 端口的变化，Before/After为对比前后的端口，不存在时为nil
+type PortDiff struct {
+	Protocol PortProtocol `json:"protocol"`
+	PortId   uint16       `json:"portid"`
+	Change   ChangeType   `json:"change"`
+	State    *ValueChange `json:"state,omitempty"`
+	Service  *ValueChange `json:"service,omitempty"`
+	//product version extrainfo
+	Version *ValueChange `json:"version,omitempty"`
+	Scripts []ScriptDiff `json:"scripts,omitempty"`
+	Before  *Port        `json:"before,omitempty"`
+	After   *Port        `json:"after,omitempty"`
+}
+
+// ScriptDiff NSE脚本输出的变化
+type ScriptDiff struct {
+	Id     string     `json:"id"`
+	Change ChangeType `json:"change"`
+	Before string     `json:"before,omitempty"`
+	After  string     `json:"after,omitempty"`
+}
+
+// ValueChange 值的变化
+type ValueChange struct {
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// SetChange 集合的变化，如cpe
+type SetChange struct {
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// Diff 对比两次扫描结果，before为旧的结果
+func Diff(before, after *NmapXMLResult) *ScanDiff {
+	diff := &ScanDiff{
+		Before: scanRef(before),
+		After:  scanRef(after),
+		Hosts:  make([]HostDiff, 0),
+	}
+	afterHosts := make(map[string]*Host)
+	for _, host := range after.Hosts() {
+		afterHosts[hostKey(host)] = host
+	}
+	seen := make(map[string]bool)
+	for _, host := range before.Hosts() {
+		key := hostKey(host)
+		seen[key] = true
+		if hostDiff := diffHost(key, host, afterHosts[key]); hostDiff != nil {
+			diff.Hosts = append(diff.Hosts, *hostDiff)
+		}
+	}
+	for _, host := range after.Hosts() {
+		if key := hostKey(host); !seen[key] {
+			seen[key] = true
+			diff.Hosts = append(diff.Hosts, *diffHost(key, nil, host))
+		}
+	}
+	return diff
+}
+
+// Empty 两次扫描结果是否没有变化
+func (d *ScanDiff) Empty() bool {
+	return len(d.Hosts) == 0
+}
+
+// WriteJSON 以json格式输出差异
+func (d *ScanDiff) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(d)
+}
+
+func scanRef(result *NmapXMLResult) ScanRef {
+	return ScanRef{Args: result.Args, Start: result.Start, StartStr: result.StartStr}
+}
+
+func hostKey(host *Host) string {
+	if ip := host.IP(); ip != "" {
+		return ip
+	}
+	return host.MAC()
+}
+
+// diffHost 对比host，before或after为nil时表示host新增或消失，没有变化时返回nil
+func diffHost(key string, before, after *Host) *HostDiff {
+	diff := &HostDiff{Address: key, Change: ChangeChanged}
+	var beforeState, afterState, beforeOS, afterOS string
+	var beforeCPE, afterCPE []string
+	var beforeScripts, afterScripts []Script
+	var beforePorts, afterPorts []*Port
+	if before != nil {
+		diff.Hostnames = before.Names()
+		beforeState, beforeOS, beforeCPE = string(before.Status.State), bestOS(before), before.CPE()
+		beforeScripts, beforePorts = before.HostScript, before.PortList()
+	} else {
+		diff.Change = ChangeAdded
+	}
+	if after != nil {
+		diff.Hostnames = after.Names()
+		afterState, afterOS, afterCPE = string(after.Status.State), bestOS(after), after.CPE()
+		afterScripts, afterPorts = after.HostScript, after.PortList()
+	} else {
+		diff.Change = ChangeRemoved
+	}
+	diff.State = valueChange(beforeState, afterState)
+	diff.OS = valueChange(beforeOS, afterOS)
+	diff.CPE = setChange(beforeCPE, afterCPE)
+	diff.Ports = diffPorts(beforePorts, afterPorts)
+	diff.Scripts = diffScripts(beforeScripts, afterScripts)
+	if diff.Change == ChangeChanged && diff.State == nil && diff.OS == nil && diff.CPE == nil &&
+		len(diff.Ports) == 0 && len(diff.Scripts) == 0 {
+		return nil
+	}
+	return diff
+}
+
+func bestOS(host *Host) string {
+	if osMatch := bestOSMatch(host); osMatch != nil {
+		return osMatch.Name
+	}
+	return ""
+}
+
+// diffPorts 对比端口，按协议和端口号排序，新增或消失的端口只有开放时才记录
+func diffPorts(before, after []*Port) []PortDiff {
+	type portKey struct {
+		protocol PortProtocol
+		id       uint16
+	}
+	ports := make(map[portKey][2]*Port)
+	for _, port := range before {
+		key := portKey{port.Protocol, port.PortId}
+		pair := ports[key]
+		pair[0] = port
+		ports[key] = pair
+	}
+	for _, port := range after {
+		key := portKey{port.Protocol, port.PortId}
+		pair := ports[key]
+		pair[1] = port
+		ports[key] = pair
+	}
+	keys := make([]portKey, 0, len(ports))
+	for key := range ports {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].protocol != keys[j].protocol {
+			return keys[i].protocol < keys[j].protocol
+		}
+		return keys[i].id < keys[j].id
+	})
+
+	var diffs []PortDiff
+	for _, key := range keys {
+		pair := ports[key]
+		if diff := diffPort(pair[0], pair[1]); diff != nil {
+			diff.Protocol, diff.PortId = key.protocol, key.id
+			diffs = append(diffs, *diff)
+		}
+	}
+	return diffs
+}
+
+func diffPort(before, after *Port) *PortDiff {
+	diff := &PortDiff{Change: ChangeChanged, Before: before, After: after}
+	var beforeState, afterState, beforeService, afterService, beforeVersion, afterVersion string
+	var beforeScripts, afterScripts []Script
+	if before != nil {
+		beforeState, beforeService, beforeVersion = string(before.State.State), before.Service.Name, serviceVersion(before.Service)
+		beforeScripts = before.Script
+	}
+	if after != nil {
+		afterState, afterService, afterVersion = string(after.State.State), after.Service.Name, serviceVersion(after.Service)
+		afterScripts = after.Script
+	}
+	beforeOpen := before != nil && before.IsOpen()
+	afterOpen := after != nil && after.IsOpen()
+	switch {
+	case !beforeOpen && afterOpen:
+		diff.Change = ChangeOpened
+	case beforeOpen && !afterOpen:
+		diff.Change = ChangeClosed
+	case before == nil || after == nil:
+		//未开放的端口出现或消失（如进入extraports）不算变化
+		return nil
+	}
+	diff.State = valueChange(beforeState, afterState)
+	diff.Service = valueChange(beforeService, afterService)
+	diff.Version = valueChange(beforeVersion, afterVersion)
+	diff.Scripts = diffScripts(beforeScripts, afterScripts)
+	if diff.Change == ChangeChanged && diff.State == nil && diff.Service == nil && diff.Version == nil && len(diff.Scripts) == 0 {
+		return nil
+	}
+	return diff
+}
+
+func serviceVersion(service Service) string {
+	var fields []string
+	for _, field := range []string{service.Product, service.Version, service.ExtraInfo} {
+		if field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return strings.Join(fields, " ")
+}
+
+// diffScripts 按脚本id对比输出
+func diffScripts(before, after []Script) []ScriptDiff {
+	afterOutput := make(map[string]string)
+	for _, script := range after {
+		afterOutput[script.Id] = script.Output
+	}
+	var diffs []ScriptDiff
+	seen := make(map[string]bool)
+	for _, script := range before {
+		seen[script.Id] = true
+		output, ok := afterOutput[script.Id]
+		switch {
+		case !ok:
+			diffs = append(diffs, ScriptDiff{Id: script.Id, Change: ChangeRemoved, Before: script.Output})
+		case output != script.Output:
+			diffs = append(diffs, ScriptDiff{Id: script.Id, Change: ChangeChanged, Before: script.Output, After: output})
+		}
+	}
+	for _, script := range after {
+		if !seen[script.Id] {
+			seen[script.Id] = true
+			diffs = append(diffs, ScriptDiff{Id: script.Id, Change: ChangeAdded, After: script.Output})
+		}
+	}
+	return diffs
+}
+
+func valueChange(before, after string) *ValueChange {
+	if before == after {
+		return nil
+	}
+	return &ValueChange{Before: before, After: after}
+}
+
+func setChange(before, after []string) *SetChange {
+	change := &SetChange{}
+	beforeSet := make(map[string]bool)
+	afterSet := make(map[string]bool)
+	for _, s := range before {
+		beforeSet[s] = true
+	}
+	for _, s := range after {
+		afterSet[s] = true
+		if !beforeSet[s] {
+			change.Added = append(change.Added, s)
+		}
+	}
+	for _, s := range before {
+		if !afterSet[s] {
+			change.Removed = append(change.Removed, s)
+		}
+	}
+	if len(change.Added)+len(change.Removed) == 0 {
+		return nil
+	}
+	return change
+}
+
+// portName 如 443/tcp
+func (d *PortDiff) portName() string {
+	return strconv.Itoa(int(d.PortId)) + "/" + string(d.Protocol)
+}
@@ -0,0 +1,159 @@
+package nmap
+
+import (
+	"bufio"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+// WriteText 以类似ndiff的文本格式输出差异，-为旧的结果，+为新的结果
+func (d *ScanDiff) WriteText(w io.Writer) error {
+	writer := bufio.NewWriter(w)
+	if d.Before.Args != d.After.Args || d.Before.StartStr != d.After.StartStr {
+		fmt.Fprintf(writer, "-Nmap scan initiated %s as: %s\n", d.Before.StartStr, d.Before.Args)
+		fmt.Fprintf(writer, "+Nmap scan initiated %s as: %s\n", d.After.StartStr, d.After.Args)
+	}
+	for _, host := range d.Hosts {
+		fmt.Fprintln(writer)
+		name := host.Address
+		if len(host.Hostnames) != 0 {
+			name += " (" + host.Hostnames[0] + ")"
+		}
+		fmt.Fprintf(writer, "%s%s:\n", hostPrefix(host.Change), name)
+		if host.State != nil {
+			writeTextChange(writer, host.State, "Host is %s.")
+		}
+		if host.OS != nil {
+			writeTextChange(writer, host.OS, "OS: %s")
+		}
+		if host.CPE != nil {
+			for _, cpe := range host.CPE.Removed {
+				fmt.Fprintf(writer, "-%s\n", cpe)
+			}
+			for _, cpe := range host.CPE.Added {
+				fmt.Fprintf(writer, "+%s\n", cpe)
+			}
+		}
+		writeTextScripts(writer, host.Scripts, "Host script results:")
+		if len(host.Ports) != 0 {
+			fmt.Fprintf(writer, " %-10s %-14s %-16s %s\n", "PORT", "STATE", "SERVICE", "VERSION")
+		}
+		for _, port := range host.Ports {
+			if port.Before != nil {
+				fmt.Fprintf(writer, "-%s\n", portLine(port.portName(), port.Before))
+			}
+			if port.After != nil {
+				fmt.Fprintf(writer, "+%s\n", portLine(port.portName(), port.After))
+			}
+			writeTextScripts(writer, port.Scripts, "")
+		}
+	}
+	return writer.Flush()
+}
+
+func hostPrefix(change ChangeType) string {
+	switch change {
+	case ChangeAdded:
+		return "+"
+	case ChangeRemoved:
+		return "-"
+	}
+	return " "
+}
+
+func writeTextChange(writer io.Writer, change *ValueChange, format string) {
+	if change.Before != "" {
+		fmt.Fprintf(writer, "-"+format+"\n", change.Before)
+	}
+	if change.After != "" {
+		fmt.Fprintf(writer, "+"+format+"\n", change.After)
+	}
+}
+
+func writeTextScripts(writer io.Writer, scripts []ScriptDiff, title string) {
+	if len(scripts) != 0 && title != "" {
+		fmt.Fprintf(writer, " %s\n", title)
+	}
+	for _, script := range scripts {
+		fmt.Fprintf(writer, " | %s:\n", script.Id)
+		writeTextOutput(writer, "-", script.Before)
+		writeTextOutput(writer, "+", script.After)
+	}
+}
+
+func writeTextOutput(writer io.Writer, prefix, output string) {
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			fmt.Fprintf(writer, "%s|   %s\n", prefix, line)
+		}
+	}
+}
+
+func portLine(name string, port *Port) string {
+	line := fmt.Sprintf("%-10s %-14s %-16s %s", name, port.State.State, port.Service.Name, serviceVersion(port.Service))
+	return strings.TrimRight(line, " ")
+}
+
+// WriteHTML 以html格式输出差异
+func (d *ScanDiff) WriteHTML(w io.Writer) error {
+	return diffTemplate.Execute(w, d)
+}
+
+var diffTemplate = template.Must(template.New("diff").Funcs(template.FuncMap{
+	"portName": func(port PortDiff) string { return port.portName() },
+	"version":  serviceVersion,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>nmap diff</title>
+<style>
+body { font-family: monospace; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+td, th { border: 1px solid #ccc; padding: 2px 8px; text-align: left; vertical-align: top; }
+.added, .opened { background: #e6ffed; }
+.removed, .closed { background: #ffeef0; }
+.changed { background: #fffbdd; }
+pre { margin: 0; white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<p>- {{.Before.StartStr}} <code>{{.Before.Args}}</code><br>+ {{.After.StartStr}} <code>{{.After.Args}}</code></p>
+{{- if not .Hosts}}
+<p>no changes</p>
+{{- end}}
+{{- range .Hosts}}
+<h3 class="{{.Change}}">{{.Address}}{{range .Hostnames}} {{.}}{{end}} ({{.Change}})</h3>
+<table>
+{{- with .State}}
+<tr><th>state</th><td>{{.Before}}</td><td>{{.After}}</td></tr>
+{{- end}}
+{{- with .OS}}
+<tr><th>os</th><td>{{.Before}}</td><td>{{.After}}</td></tr>
+{{- end}}
+{{- with .CPE}}
+<tr><th>cpe</th><td class="removed">{{range .Removed}}{{.}}<br>{{end}}</td><td class="added">{{range .Added}}{{.}}<br>{{end}}</td></tr>
+{{- end}}
+{{- range .Scripts}}
+<tr class="{{.Change}}"><th>{{.Id}}</th><td><pre>{{.Before}}</pre></td><td><pre>{{.After}}</pre></td></tr>
+{{- end}}
+</table>
+{{- if .Ports}}
+<table>
+<tr><th>port</th><th>change</th><th>before</th><th>after</th></tr>
+{{- range .Ports}}
+<tr class="{{.Change}}"><td>{{portName .}}</td><td>{{.Change}}</td>
+<td>{{with .Before}}{{.State.State}} {{.Service.Name}} {{version .Service}}{{end}}</td>
+<td>{{with .After}}{{.State.State}} {{.Service.Name}} {{version .Service}}{{end}}</td></tr>
+{{- range .Scripts}}
+<tr class="{{.Change}}"><td></td><td>{{.Id}}</td><td><pre>{{.Before}}</pre></td><td><pre>{{.After}}</pre></td></tr>
+{{- end}}
+{{- end}}
+</table>
+{{- end}}
+{{- end}}
+</body>
+</html>
+`))
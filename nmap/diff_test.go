@@ -0,0 +1,107 @@
+package nmap
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	before := loadXML(t, "testdata/scanme.xml")
+	if diff := Diff(before, loadXML(t, "testdata/scanme.xml")); !diff.Empty() {
+		t.Fatalf("expected no changes, but got %+v", diff.Hosts)
+	}
+
+	after := loadXML(t, "testdata/scanme.xml")
+	scanme := &after.Host[0]
+	ports := scanme.Ports[0].Port
+	ports[1].Service.Version = "2.4.52"
+	ports[1].Script[0].Output = "ScanMe again"
+	ports[2].State.State = PortState(Closed)
+	ports[4].State.State = PortState(Open)
+	scanme.Ports[0].Port = append(ports[:3], ports[4])
+	scanme.OS[0].OSMatch[0].Name = "Linux 5.4"
+	//192.168.1.10消失，192.168.1.20新增
+	after.Host[1] = Host{
+		Status:  Status{State: HostStateUp},
+		Address: []Address{{Addr: "192.168.1.20", AddrType: "ipv4"}},
+		Ports: []Ports{{Port: []Port{
+			{Protocol: PortProtocolTcp, PortId: 8080, State: State{State: PortState(Open)}, Service: Service{Name: "http-proxy"}},
+			{Protocol: PortProtocolTcp, PortId: 8443, State: State{State: PortState(Filtered)}},
+		}}},
+	}
+
+	diff := Diff(before, after)
+	if len(diff.Hosts) != 3 {
+		t.Fatalf("expected 3 host changes, but got %+v", diff.Hosts)
+	}
+	changed, removed, added := diff.Hosts[0], diff.Hosts[1], diff.Hosts[2]
+	if changed.Address != "45.33.32.156" || changed.Change != ChangeChanged || changed.OS.After != "Linux 5.4" || changed.State != nil {
+		t.Errorf("unexpected changed host %+v", changed)
+	}
+	if len(changed.Ports) != 4 {
+		t.Fatalf("expected 4 port changes, but got %+v", changed.Ports)
+	}
+	if port := changed.Ports[0]; port.PortId != 80 || port.Change != ChangeChanged || port.Version.After != "Apache httpd 2.4.52 (Ubuntu)" ||
+		len(port.Scripts) != 1 || port.Scripts[0].After != "ScanMe again" {
+		t.Errorf("unexpected port 80 %+v", port)
+	}
+	if port := changed.Ports[1]; port.PortId != 443 || port.Change != ChangeClosed || port.State.After != "closed" {
+		t.Errorf("unexpected port 443 %+v", port)
+	}
+	//9929从结果中消失
+	if port := changed.Ports[2]; port.PortId != 9929 || port.Change != ChangeClosed || port.After != nil {
+		t.Errorf("unexpected port 9929 %+v", port)
+	}
+	if port := changed.Ports[3]; port.PortId != 31337 || port.Change != ChangeOpened {
+		t.Errorf("unexpected port 31337 %+v", port)
+	}
+	if changed.CPE == nil || len(changed.CPE.Removed) != 1 || changed.CPE.Removed[0] != "cpe:/a:igor_sysoev:nginx:1.18.0" {
+		t.Errorf("unexpected cpe %+v", changed.CPE)
+	}
+	if removed.Address != "192.168.1.10" || removed.Change != ChangeRemoved || len(removed.Ports) != 3 || removed.Ports[0].Change != ChangeClosed {
+		t.Errorf("unexpected removed host %+v", removed)
+	}
+	//未开放的8443不记录
+	if added.Address != "192.168.1.20" || added.Change != ChangeAdded || len(added.Ports) != 1 || added.Ports[0].Change != ChangeOpened {
+		t.Errorf("unexpected added host %+v", added)
+	}
+
+	var text bytes.Buffer
+	if err := diff.WriteText(&text); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		" 45.33.32.156 (scanme.nmap.org):",
+		"-80/tcp     open           http             Apache httpd 2.4.7 (Ubuntu)",
+		"+80/tcp     open           http             Apache httpd 2.4.52 (Ubuntu)",
+		"+|   ScanMe again",
+		"+443/tcp    closed         http             nginx 1.18.0",
+		"-192.168.1.10 (fileserver.corp.example):",
+		"+192.168.1.20:",
+		"+Host is up.",
+		"+8080/tcp   open           http-proxy",
+	} {
+		if !strings.Contains(text.String(), line+"\n") {
+			t.Errorf("expected line %q in\n%s", line, text.String())
+		}
+	}
+
+	var html bytes.Buffer
+	if err := diff.WriteHTML(&html); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(html.String(), `<h3 class="added">192.168.1.20 (added)</h3>`) {
+		t.Errorf("unexpected html\n%s", html.String())
+	}
+
+	var content bytes.Buffer
+	if err := diff.WriteJSON(&content); err != nil {
+		t.Fatal(err)
+	}
+	var decoded ScanDiff
+	if err := json.Unmarshal(content.Bytes(), &decoded); err != nil || len(decoded.Hosts) != 3 || decoded.Hosts[2].Ports[0].After.PortId != 8080 {
+		t.Errorf("unexpected json %v %+v", err, decoded)
+	}
+}
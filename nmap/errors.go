@@ -0,0 +1,77 @@
+package nmap
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// 可通过errors.Is判断的错误类型
+var (
+	//未找到nmap可执行文件
+	ErrNmapNotFound = errors.New("nmap not found")
+	//context超时，扫描被终止
+	ErrScanTimeout = errors.New("nmap scan timeout")
+	//context被取消，扫描被终止
+	ErrScanCanceled = errors.New("nmap scan canceled")
+	//xml结果解析失败
+	ErrXMLParse = errors.New("nmap xml parse error")
+	//-oG、-oN结果解析失败
+	ErrOutputParse = errors.New("nmap output parse error")
+	//未注册的导出格式
+	ErrUnknownFormat = errors.New("unknown export format")
+	//未添加的profile
+	ErrUnknownProfile = errors.New("unknown scan profile")
+	//无法解析的目标说明
+	ErrInvalidTarget = errors.New("invalid nmap target")
+	//目标的地址数超过限制
+	ErrTooManyTargets = errors.New("too many nmap targets")
+	//ParseCommand中未知的参数
+	ErrUnknownOption = errors.New("unknown nmap option")
+	//参数检查未通过，具体问题见ValidationErrors
+	ErrInvalidOption = errors.New("invalid nmap option")
+)
+
+// ErrNmapExit nmap运行失败，退出码非0或xml结果中包含errormsg，可通过errors.As获取
+type ErrNmapExit struct {
+	//退出码
+	Code int
+	//标准错误输出
+	Stderr string
+	//xml结果中runstats的errormsg
+	ErrorMsg string
+}
+
+func (e *ErrNmapExit) Error() string {
+	msg := e.ErrorMsg
+	if msg == "" {
+		msg = e.Stderr
+	}
+	return fmt.Sprintf("nmap exit with code %d: %s", e.Code, msg)
+}
+
+// XMLParseError xml解析失败的具体原因，errors.Is(err, ErrXMLParse)为true
+type XMLParseError struct {
+	Err error
+}
+
+func (e *XMLParseError) Error() string {
+	return ErrXMLParse.Error() + ": " + e.Err.Error()
+}
+
+func (e *XMLParseError) Unwrap() error {
+	return e.Err
+}
+
+func (e *XMLParseError) Is(target error) bool {
+	return target == ErrXMLParse
+}
+
+// ctxErr 将context的错误转换为ErrScanTimeout或ErrScanCanceled
+func ctxErr(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrScanTimeout
+	}
+	return ErrScanCanceled
+}
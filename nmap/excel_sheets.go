@@ -0,0 +1,335 @@
+package nmap
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/xuri/excelize/v2"
+)
+
+// ExportResult中由config控制的附加表
+
+var (
+	osHeader     = []string{"address", "hostnames", "os", "accuracy", "vendor", "osfamily", "osgen", "type", "cpe", "distance", "uptime", "lastboot"}
+	scriptHeader = []string{"scope", "address", "hostnames", "script", "output"}
+	traceHeader  = []string{"address", "hostnames", "proto", "port", "ttl", "rtt", "ipaddr", "host"}
+	//其后为每个host一列
+	serviceHeader = []string{"service", "open ports", "hosts", "products"}
+)
+
+// summarySheet 概况表的名称，饼图引用该表的数据
+const summarySheet = "summary"
+
+// streamSheet 通过StreamWriter写入的附加表
+type streamSheet struct {
+	name   string
+	header []string
+	//各列宽度，未指定的列使用默认宽度
+	widths map[int]float64
+	rows   [][]any
+}
+
+// exportSheets 按配置增加附加表
+func exportSheets(file *excelize.File, result *NmapXMLResult, option config) error {
+	var sheets []streamSheet
+	if option.OSSheet {
+		sheets = append(sheets, streamSheet{"os", osHeader, map[int]float64{1: 20, 2: 30, 3: 40, 9: 40}, osRows(result)})
+	}
+	if option.ScriptSheet {
+		sheets = append(sheets, streamSheet{"scripts", scriptHeader, map[int]float64{2: 20, 3: 30, 4: 25, 5: 100}, scriptRows(result)})
+	}
+	if option.TraceSheet {
+		sheets = append(sheets, streamSheet{"traceroute", traceHeader, map[int]float64{1: 20, 2: 30, 7: 20, 8: 30}, traceRows(result)})
+	}
+	if option.ServiceSheet {
+		sheets = append(sheets, serviceSheet(result))
+	}
+	if option.SummarySheet {
+		if err := writeSummarySheet(file, result); err != nil {
+			return err
+		}
+	}
+	for _, sheet := range sheets {
+		if err := writeStreamSheet(file, sheet, option.AddTable); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newSheet 创建表，NewSheet在表已存在时返回已有表的序号，此时返回错误，避免写入已有的表
+func newSheet(file *excelize.File, name string) error {
+	if file.GetSheetIndex(name) != -1 {
+		return errors.Errorf("sheet %q already exists", name)
+	}
+	if file.NewSheet(name) == -1 {
+		return errors.Errorf("create sheet %q", name)
+	}
+	return nil
+}
+
+// writeStreamSheet 创建表并通过StreamWriter写入表头和各行，addTable时将数据区域设置为表格
+func writeStreamSheet(file *excelize.File, sheet streamSheet, addTable bool) error {
+	if err := newSheet(file, sheet.name); err != nil {
+		return err
+	}
+	writer, err := file.NewStreamWriter(sheet.name)
+	if err != nil {
+		return err
+	}
+	for col, width := range sheet.widths {
+		if err := setColWidth(writer, width, col); err != nil {
+			return err
+		}
+	}
+	if err := writeHeader(writer, sheet.header); err != nil {
+		return err
+	}
+	for i, row := range sheet.rows {
+		if err := writeValue(writer, i+2, row); err != nil {
+			return err
+		}
+	}
+	if addTable && len(sheet.rows) != 0 {
+		lastCell, err := excelize.CoordinatesToCellName(len(sheet.header), len(sheet.rows)+1)
+		if err != nil {
+			return err
+		}
+		tableFormat := fmt.Sprintf(`{"table_name": "%s", "table_style": "TableStyleMedium2", "show_row_stripes": true}`, sheet.name)
+		if err := writer.AddTable("A1", lastCell, tableFormat); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
+
+// writeSummarySheet 概况表：扫描参数、时间、host数、端口状态（饼图）和常见服务
+func writeSummarySheet(file *excelize.File, result *NmapXMLResult) error {
+	if err := newSheet(file, summarySheet); err != nil {
+		return err
+	}
+	finished := result.RunStats.Finished
+	rows := [][]any{
+		{"scanner", result.Scanner},
+		{"version", result.Version},
+		{"args", result.Args},
+		{"start", result.StartStr},
+		{"finished", finished.TimeStr},
+		{"elapsed", finished.Elapsed},
+		{"exit", finished.Exit},
+		{"summary", finished.Summary},
+		{"hosts up", result.RunStats.Hosts.Up},
+		{"hosts down", result.RunStats.Hosts.Down},
+		{"hosts total", result.RunStats.Hosts.Total},
+	}
+	if finished.ErrorMsg != "" {
+		rows = append(rows, []any{"errormsg", finished.ErrorMsg})
+	}
+	for _, scanInfo := range result.ScanInfo {
+		rows = append(rows, []any{"scaninfo", fmt.Sprintf("%s/%s %d ports", scanInfo.Type, scanInfo.Protocol, scanInfo.NumServices)})
+	}
+
+	//端口状态，包括未列出的extraports
+	rows = append(rows, nil, []any{"port state", "count"})
+	stateStart := len(rows) + 1
+	for _, state := range portStateCounts(result) {
+		rows = append(rows, []any{state.Name, state.Count})
+	}
+	stateEnd := len(rows)
+
+	//常见服务
+	rows = append(rows, nil, []any{"top services", "open ports"})
+	services := serviceCounts(result)
+	if len(services) > 10 {
+		services = services[:10]
+	}
+	for _, service := range services {
+		rows = append(rows, []any{service.Name, service.Count})
+	}
+
+	for i, row := range rows {
+		if row == nil {
+			continue
+		}
+		if err := file.SetSheetRow(summarySheet, "A"+strconv.Itoa(i+1), &row); err != nil {
+			return err
+		}
+	}
+	if err := file.SetColWidth(summarySheet, "A", "A", 15); err != nil {
+		return err
+	}
+	if err := file.SetColWidth(summarySheet, "B", "B", 80); err != nil {
+		return err
+	}
+	if stateEnd < stateStart {
+		return nil
+	}
+	chart := fmt.Sprintf(`{
+		"type": "pie",
+		"series": [{"name": "%[1]s!$A$%[2]d", "categories": "%[1]s!$A$%[3]d:$A$%[4]d", "values": "%[1]s!$B$%[3]d:$B$%[4]d"}],
+		"title": {"name": "port state"},
+		"plotarea": {"show_percent": true}
+	}`, summarySheet, stateStart-1, stateStart, stateEnd)
+	return file.AddChart(summarySheet, "D2", chart)
+}
+
+// portStateCounts 各端口状态的数量，包括extraports，按数量从多到少排序
+func portStateCounts(result *NmapXMLResult) []serviceCount {
+	counts := make(map[string]int)
+	for _, host := range result.Hosts() {
+		for _, ports := range host.Ports {
+			for _, extraPorts := range ports.ExtraPorts {
+				counts[string(extraPorts.State)] += extraPorts.Count
+			}
+		}
+		for _, port := range host.PortList() {
+			counts[string(port.State.State)]++
+		}
+	}
+	return sortCounts(counts)
+}
+
+// serviceCounts 各服务开放端口的数量，按数量从多到少排序
+func serviceCounts(result *NmapXMLResult) []serviceCount {
+	counts := make(map[string]int)
+	for _, hostPort := range result.OpenPorts() {
+		counts[hostPort.Port.Service.Name]++
+	}
+	return sortCounts(counts)
+}
+
+func sortCounts(counts map[string]int) []serviceCount {
+	sorted := make([]serviceCount, 0, len(counts))
+	for name, count := range counts {
+		sorted = append(sorted, serviceCount{Name: name, Count: count})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Count != sorted[j].Count {
+			return sorted[i].Count > sorted[j].Count
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted
+}
+
+// osRows 每个host准确率最高的操作系统识别结果
+func osRows(result *NmapXMLResult) [][]any {
+	var rows [][]any
+	for _, host := range result.Hosts() {
+		osMatch := bestOSMatch(host)
+		if osMatch == nil {
+			continue
+		}
+		row := make([]any, len(osHeader))
+		copy(row, hostRow(host.Address, host.Hostnames, host.Status)[:2])
+		row[2] = osMatch.Name
+		row[3] = osMatch.Accuracy
+		var vendor, family, gen, osType, cpe []string
+		for _, osClass := range osMatch.OSClass {
+			vendor = append(vendor, osClass.Vendor)
+			family = append(family, osClass.OSFamily)
+			gen = append(gen, osClass.OSGen)
+			osType = append(osType, osClass.Type)
+			cpe = append(cpe, osClass.CPE...)
+		}
+		row[4] = strings.Join(vendor, "\n")
+		row[5] = strings.Join(family, "\n")
+		row[6] = strings.Join(gen, "\n")
+		row[7] = strings.Join(osType, "\n")
+		row[8] = strings.Join(cpe, "\n")
+		for _, distance := range host.Distance {
+			row[9] = distance.Value
+		}
+		for _, uptime := range host.Uptime {
+			row[10] = uptime.Seconds
+			row[11] = uptime.LastBoot
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// scriptRows prescript、hostscript和postscript，端口脚本在host And Ports表的nseresult列中
+func scriptRows(result *NmapXMLResult) [][]any {
+	var rows [][]any
+	for _, script := range result.Prescript {
+		rows = append(rows, []any{"prescript", nil, nil, script.Id, script.Output})
+	}
+	for _, host := range result.Hosts() {
+		hostCols := hostRow(host.Address, host.Hostnames, host.Status)
+		for _, script := range host.HostScript {
+			rows = append(rows, []any{"host", hostCols[0], hostCols[1], script.Id, script.Output})
+		}
+	}
+	for _, script := range result.Postscript {
+		rows = append(rows, []any{"postscript", nil, nil, script.Id, script.Output})
+	}
+	return rows
+}
+
+// traceRows 每一跳一行
+func traceRows(result *NmapXMLResult) [][]any {
+	var rows [][]any
+	for _, host := range result.Hosts() {
+		hostCols := hostRow(host.Address, host.Hostnames, host.Status)
+		for _, trace := range host.Trace {
+			for _, hop := range trace.Hop {
+				var rtt any = "--"
+				if hop.RTT.Known() {
+					rtt = float64(hop.RTT)
+				}
+				rows = append(rows, []any{hostCols[0], hostCols[1], trace.Proto, trace.Port, hop.TTL, rtt, hop.Ipaddr, hop.Host})
+			}
+		}
+	}
+	return rows
+}
+
+// serviceSheet 服务与host的透视表，每个服务一行，每个host一列，单元格为该host上该服务的开放端口，按开放端口数从多到少排序
+func serviceSheet(result *NmapXMLResult) streamSheet {
+	type service struct {
+		//host的地址 => 端口
+		ports    map[string][]string
+		products []string
+		count    int
+	}
+	var addrs []string
+	services := make(map[string]*service)
+	for _, hostPort := range result.OpenPorts() {
+		port := hostPort.Port
+		addr := hostPort.Host.IP()
+		if !contains(addrs, addr) {
+			addrs = append(addrs, addr)
+		}
+		s, ok := services[port.Service.Name]
+		if !ok {
+			s = &service{ports: make(map[string][]string)}
+			services[port.Service.Name] = s
+		}
+		s.count++
+		s.ports[addr] = append(s.ports[addr], strconv.Itoa(int(port.PortId))+"/"+string(port.Protocol))
+		if version := serviceVersion(port.Service); version != "" && !contains(s.products, version) {
+			s.products = append(s.products, version)
+		}
+	}
+	sheet := streamSheet{
+		name:   "services",
+		header: append(append([]string(nil), serviceHeader...), addrs...),
+		widths: map[int]float64{1: 20, 4: 50},
+	}
+	for i := range addrs {
+		sheet.widths[len(serviceHeader)+i+1] = 20
+	}
+	for _, count := range serviceCounts(result) {
+		s := services[count.Name]
+		row := []any{count.Name, s.count, len(s.ports), strings.Join(s.products, "\n")}
+		for _, addr := range addrs {
+			row = append(row, strings.Join(s.ports[addr], ","))
+		}
+		sheet.rows = append(sheet.rows, row)
+	}
+	return sheet
+}
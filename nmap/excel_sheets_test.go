@@ -0,0 +1,69 @@
+package nmap
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestExportSheets(t *testing.T) {
+	result := loadXML(t, "testdata/scanme.xml")
+	cfg := NewConfig()
+	cfg.ResultName = filepath.Join(t.TempDir(), "Result")
+	cfg.SummarySheet, cfg.OSSheet, cfg.ScriptSheet, cfg.TraceSheet, cfg.ServiceSheet = true, true, true, true, true
+	if err := NewNmap(cfg).ExportResult(result); err != nil {
+		t.Fatal(err)
+	}
+	file, err := excelize.OpenFile(cfg.ResultName + ".xlsx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	expected := []string{"hosthint", "host And Ports", summarySheet, "os", "scripts", "traceroute", "services"}
+	if sheets := file.GetSheetList(); !reflect.DeepEqual(sheets, expected) {
+		t.Fatalf("expected sheets %v, but got %v", expected, sheets)
+	}
+
+	rows, _ := file.GetRows("os")
+	if len(rows) != 3 || rows[1][2] != "Linux 4.15 - 5.6" || rows[1][3] != "95" || rows[2][2] != "Microsoft Windows Server 2016" {
+		t.Errorf("unexpected os sheet %q", rows)
+	}
+	rows, _ = file.GetRows("scripts")
+	if len(rows) != 6 || rows[1][0] != "prescript" || rows[2][3] != "dns-nsid" || rows[5][0] != "postscript" {
+		t.Errorf("unexpected scripts sheet %q", rows)
+	}
+	rows, _ = file.GetRows("traceroute")
+	if len(rows) != 4 || rows[3][6] != "45.33.32.156" || rows[3][5] != "154.33" {
+		t.Errorf("unexpected traceroute sheet %q", rows)
+	}
+	rows, _ = file.GetRows("services")
+	//每个host一列，单元格为该host上该服务的端口
+	if len(rows) != 7 || !reflect.DeepEqual(rows[0][4:], []string{"45.33.32.156", "192.168.1.10"}) ||
+		rows[1][0] != "http" || rows[1][1] != "2" || rows[1][4] != "80/tcp,443/tcp" || rows[2][4] != "" || rows[2][5] != "445/tcp" {
+		t.Errorf("unexpected services sheet %q", rows)
+	}
+	if value, _ := file.GetCellValue(summarySheet, "B11"); value != "2" {
+		t.Errorf("expected hosts total 2, but got %q", value)
+	}
+	if value, _ := file.GetCellValue(summarySheet, "A14"); value != "port state" {
+		t.Errorf("expected port state table, but got %q", value)
+	}
+
+	//默认不增加
+	resultName := cfg.ResultName
+	cfg = NewConfig()
+	cfg.ResultName = resultName
+	if err := NewNmap(cfg).ExportResult(result); err != nil {
+		t.Fatal(err)
+	}
+	file, err = excelize.OpenFile(cfg.ResultName + ".xlsx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if sheets := file.GetSheetList(); len(sheets) != 2 {
+		t.Errorf("expected 2 sheets, but got %v", sheets)
+	}
+}
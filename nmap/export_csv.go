@@ -0,0 +1,128 @@
+package nmap
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+)
+
+// CSVLayout host And Ports表的布局
+type CSVLayout int
+
+const (
+	//与Excel合并行一致，同一host只在第一行输出address hostnames _state _reason
+	CSVMergedHost CSVLayout = iota
+	//每一行都输出完整的host信息
+	CSVDenormalized
+)
+
+// CSVOption csv导出选项，零值为逗号分隔、合并host
+type CSVOption struct {
+	//分隔符，为0时使用逗号
+	Comma  rune
+	Layout CSVLayout
+	//端口有多个cpe时每个cpe一行
+	ExpandCPE bool
+}
+
+// ExportCSV 导出成csv，与Excel的两个表对应，ResultName.csv为host And Ports，ResultName_hosthint.csv为hosthint
+//
+// 未指定option时，布局由配置的MergeRow决定
+func (receiver *nmap) ExportCSV(result *NmapXMLResult, option ...CSVOption) error {
+	opt := receiver.csvOption()
+	if len(option) != 0 {
+		opt = option[0]
+	}
+	_, err := receiver.writeSink(receiver.exportOption.ResultName+"_hosthint.csv", func(w io.Writer) error {
+		return WriteHostHintCSV(w, result, opt)
+	})
+	if err != nil {
+		return err
+	}
+	_, err = receiver.writeSink(receiver.exportOption.ResultName+".csv", func(w io.Writer) error {
+		return WriteCSV(w, result, opt)
+	})
+	return err
+}
+
+// csvOption 由配置的MergeRow决定布局
+func (receiver *nmap) csvOption() CSVOption {
+	var opt CSVOption
+	if !receiver.exportOption.MergeRow {
+		opt.Layout = CSVDenormalized
+	}
+	return opt
+}
+
+// WriteHostHintCSV 输出hosthint表，没有hosthint时为host
+func WriteHostHintCSV(w io.Writer, result *NmapXMLResult, option CSVOption) error {
+	writer := newCSVWriter(w, option)
+	if err := writer.Write(hostHeader); err != nil {
+		return err
+	}
+	for _, row := range hostRows(result) {
+		if err := writer.Write(csvRecord(row)); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteCSV 输出host And Ports表，包含多行的NSE结果会被引号包含
+func WriteCSV(w io.Writer, result *NmapXMLResult, option CSVOption) error {
+	writer := newCSVWriter(w, option)
+	if err := writer.Write(portHeader); err != nil {
+		return err
+	}
+	for _, group := range portRowGroups(result) {
+		first := true
+		for _, row := range group {
+			for _, record := range expandCPE(csvRecord(row), option.ExpandCPE) {
+				if option.Layout == CSVMergedHost && !first {
+					for i := 0; i < len(hostHeader); i++ {
+						record[i] = ""
+					}
+				}
+				first = false
+				if err := writer.Write(record); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func newCSVWriter(w io.Writer, option CSVOption) *csv.Writer {
+	writer := csv.NewWriter(w)
+	if option.Comma != 0 {
+		writer.Comma = option.Comma
+	}
+	return writer
+}
+
+func csvRecord(row []any) []string {
+	record := make([]string, len(row))
+	for i, value := range row {
+		record[i] = cellString(value)
+	}
+	return record
+}
+
+// expandCPE 将cpe列拆分为每个cpe一行
+func expandCPE(record []string, expand bool) [][]string {
+	const cpeCol = 10
+	cpes := strings.Split(record[cpeCol], "\n")
+	if !expand || len(cpes) < 2 {
+		return [][]string{record}
+	}
+	records := make([][]string, 0, len(cpes))
+	for _, cpe := range cpes {
+		r := append([]string(nil), record...)
+		r[cpeCol] = cpe
+		records = append(records, r)
+	}
+	return records
+}
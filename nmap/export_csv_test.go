@@ -0,0 +1,73 @@
+package nmap
+
+import (
+	"bytes"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readCSV(t *testing.T, content []byte, comma rune) [][]string {
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.Comma = comma
+	records, err := reader.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return records
+}
+
+func TestWriteCSV(t *testing.T) {
+	result := loadXML(t, "testdata/scanme.xml")
+
+	var merged bytes.Buffer
+	if err := WriteCSV(&merged, result, CSVOption{}); err != nil {
+		t.Fatal(err)
+	}
+	records := readCSV(t, merged.Bytes(), ',')
+	//表头 + 5 + 4个端口
+	if len(records) != 10 || len(records[0]) != len(portHeader) {
+		t.Fatalf("unexpected records %d", len(records))
+	}
+	if records[1][0] != "45.33.32.156" || records[2][0] != "" || records[6][0] != "192.168.1.10\n00:0C:29:3E:5A:11" {
+		t.Errorf("unexpected merged layout %q %q %q", records[1][0], records[2][0], records[6][0])
+	}
+	//多行的nse结果
+	if nse := records[1][13]; nse[:12] != "ssh-hostkey\n" || records[1][10] != "cpe:/a:openbsd:openssh:6.6.1p1\ncpe:/o:linux:linux_kernel" {
+		t.Errorf("unexpected nse %q", nse)
+	}
+
+	var denormalized bytes.Buffer
+	if err := WriteCSV(&denormalized, result, CSVOption{Comma: ';', Layout: CSVDenormalized, ExpandCPE: true}); err != nil {
+		t.Fatal(err)
+	}
+	records = readCSV(t, denormalized.Bytes(), ';')
+	if len(records) != 11 || records[2][0] != "45.33.32.156" || records[1][10] != "cpe:/a:openbsd:openssh:6.6.1p1" ||
+		records[2][10] != "cpe:/o:linux:linux_kernel" || records[2][4] != "22" {
+		t.Errorf("unexpected denormalized layout %q", records[:3])
+	}
+}
+
+func TestExportCSV(t *testing.T) {
+	cfg := NewConfig()
+	cfg.ResultName = filepath.Join(t.TempDir(), "Result")
+	if err := NewNmap(cfg).ExportCSV(loadXML(t, "testdata/discovery.xml")); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(cfg.ResultName + "_hosthint.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if records := readCSV(t, content, ','); len(records) != 3 || records[1][2] != "up" {
+		t.Errorf("unexpected hosthint %q", records)
+	}
+	content, err = os.ReadFile(cfg.ResultName + ".csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	//没有端口的host各一行
+	if records := readCSV(t, content, ','); len(records) != 5 || records[2][0] != "192.168.1.2" || records[2][2] != "down" {
+		t.Errorf("unexpected host and ports %q", records)
+	}
+}
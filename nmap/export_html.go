@@ -0,0 +1,52 @@
+package nmap
+
+import (
+	"context"
+	_ "embed"
+	"html/template"
+	"io"
+	"strings"
+)
+
+//go:embed templates/report.html
+var reportHTML string
+
+// reportTemplate html报告，样式和脚本均内嵌，可离线查看
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"join":    strings.Join,
+	"version": serviceVersion,
+	"hostID": func(host *Host) string {
+		return "host-" + strings.NewReplacer(".", "-", ":", "-").Replace(hostKey(host))
+	},
+}).Parse(reportHTML))
+
+// reportData html报告的数据
+type reportData struct {
+	Result    *NmapXMLResult
+	Hosts     HostSet
+	OpenPorts []HostPort
+	//按开放端口数从多到少排序
+	Services []serviceCount
+}
+
+type serviceCount struct {
+	Name  string
+	Count int
+}
+
+// ExportHTML 导出成html报告，包括扫描概况、开放端口汇总（可排序、过滤）和每个host的端口、操作系统、traceroute、脚本结果
+func (receiver *nmap) ExportHTML(result *NmapXMLResult) error {
+	_, err := receiver.ExportFile(context.Background(), "html", result)
+	return err
+}
+
+// WriteHTML 输出html报告
+func WriteHTML(w io.Writer, result *NmapXMLResult) error {
+	data := reportData{
+		Result:    result,
+		Hosts:     result.Hosts(),
+		OpenPorts: result.OpenPorts(),
+		Services:  serviceCounts(result),
+	}
+	return reportTemplate.Execute(w, data)
+}
@@ -0,0 +1,43 @@
+package nmap
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteHTML(t *testing.T) {
+	for _, fileName := range xmlCorpus(t) {
+		var content bytes.Buffer
+		if err := WriteHTML(&content, loadXML(t, fileName)); err != nil {
+			t.Fatalf("%s: %v", fileName, err)
+		}
+	}
+
+	var content bytes.Buffer
+	if err := WriteHTML(&content, loadXML(t, "testdata/scanme.xml")); err != nil {
+		t.Fatal(err)
+	}
+	report := content.String()
+	for _, expected := range []string{
+		`<div class="value">7</div><div class="label">open ports</div>`,
+		`<tr><td>http</td><td>2</td></tr>`,
+		`<a href="#host-45-33-32-156">45.33.32.156</a>`,
+		`<div class="host up" id="host-192-168-1-10">`,
+		`<details><summary>ssl-cert</summary><pre>Subject: commonName=scanme.nmap.org`,
+		`<tr><td>Linux 4.15 - 5.6</td><td>95</td>`,
+		`<tr><td>2</td><td>8.61</td><td>10.10.0.1 (gw.isp.example)</td></tr>`,
+		`uptime:`,
+		`<details><summary>post-scan ssh-hostkey</summary>`,
+	} {
+		if !strings.Contains(report, expected) {
+			t.Errorf("expected %q in report", expected)
+		}
+	}
+	//不引用外部资源
+	for _, external := range []string{"<link ", "src=\"http", "@import"} {
+		if strings.Contains(report, external) {
+			t.Errorf("unexpected external asset %q", external)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package nmap
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// json导出的schema版本，字段有不兼容的变化时递增
+const (
+	JSONSchema  = "nmap-go/result/v1"
+	JSONLSchema = "nmap-go/port/v1"
+)
+
+// JSONDocument ExportJSON导出的完整结果
+type JSONDocument struct {
+	//固定为JSONSchema
+	Schema string         `json:"schema"`
+	Result *NmapXMLResult `json:"result"`
+}
+
+// PortRecord ExportJSONL导出的一行，每个开放端口一行
+//
+//	{"schema":"nmap-go/port/v1","address":"45.33.32.156","mac":"","hostnames":["scanme.nmap.org"],"port":22,"protocol":"tcp",
+//	 "state":"open","reason":"syn-ack","service":"ssh","product":"OpenSSH","version":"6.6.1p1 Ubuntu 2ubuntu2.13",
+//	 "extrainfo":"Ubuntu Linux; protocol 2.0","tunnel":"","confidence":10,"cpe":["cpe:/a:openbsd:openssh:6.6.1p1"],
+//	 "scripts":[{"id":"banner","output":"SSH-2.0-OpenSSH_6.6.1p1 Ubuntu-2ubuntu2.13"}],
+//	 "scan_start":"2022-04-18T02:00:00Z","args":"nmap ..."}
+type PortRecord struct {
+	//固定为JSONLSchema
+	Schema string `json:"schema"`
+	//ip地址
+	Address string `json:"address"`
+	//mac地址，仅局域网扫描时存在
+	MAC        string   `json:"mac"`
+	Hostnames  []string `json:"hostnames"`
+	Port       uint16   `json:"port"`
+	Protocol   string   `json:"protocol"`
+	State      string   `json:"state"`
+	Reason     string   `json:"reason"`
+	Service    string   `json:"service"`
+	Product    string   `json:"product"`
+	Version    string   `json:"version"`
+	ExtraInfo  string   `json:"extrainfo"`
+	Tunnel     string   `json:"tunnel"`
+	Confidence int      `json:"confidence"`
+	CPE        []string `json:"cpe"`
+	//端口脚本结果，不包含主机脚本
+	Scripts []ScriptRecord `json:"scripts"`
+	//扫描开始时间，UTC
+	ScanStart time.Time `json:"scan_start"`
+	//扫描参数
+	Args string `json:"args"`
+}
+
+// ScriptRecord PortRecord中的脚本结果
+type ScriptRecord struct {
+	Id     string `json:"id"`
+	Output string `json:"output"`
+	//结构化输出，见Script.Value，没有结构化输出时省略
+	Data interface{} `json:"data,omitempty"`
+}
+
+// ExportJSON 导出完整结果到json文件
+func (receiver *nmap) ExportJSON(result *NmapXMLResult) error {
+	_, err := receiver.ExportFile(context.Background(), "json", result)
+	return err
+}
+
+// ExportJSONL 导出开放端口到json lines文件，每个开放端口一行
+func (receiver *nmap) ExportJSONL(result *NmapXMLResult) error {
+	_, err := receiver.ExportFile(context.Background(), "jsonl", result)
+	return err
+}
+
+// WriteJSON 输出完整结果
+func WriteJSON(w io.Writer, result *NmapXMLResult) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(JSONDocument{Schema: JSONSchema, Result: result})
+}
+
+// WriteJSONL 输出开放端口，每个开放端口一行
+func WriteJSONL(w io.Writer, result *NmapXMLResult) error {
+	writer := bufio.NewWriter(w)
+	encoder := json.NewEncoder(writer)
+	for _, record := range PortRecords(result) {
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
+
+// PortRecords 将结果展开为每个开放端口一条记录
+func PortRecords(result *NmapXMLResult) []PortRecord {
+	var scanStart time.Time
+	if result.Start != 0 {
+		scanStart = time.Unix(result.Start, 0).UTC()
+	}
+	var records []PortRecord
+	for _, hostPort := range result.OpenPorts() {
+		host, port := hostPort.Host, hostPort.Port
+		record := PortRecord{
+			Schema:     JSONLSchema,
+			Address:    host.IP(),
+			MAC:        host.MAC(),
+			Hostnames:  host.Names(),
+			Port:       port.PortId,
+			Protocol:   string(port.Protocol),
+			State:      string(port.State.State),
+			Reason:     port.State.Reason,
+			Service:    port.Service.Name,
+			Product:    port.Service.Product,
+			Version:    port.Service.Version,
+			ExtraInfo:  port.Service.ExtraInfo,
+			Tunnel:     port.Service.Tunnel,
+			Confidence: int(port.Service.Conf),
+			CPE:        append([]string{}, port.Service.CPE...),
+			Scripts:    make([]ScriptRecord, 0, len(port.Script)),
+			ScanStart:  scanStart,
+			Args:       result.Args,
+		}
+		for _, script := range port.Script {
+			scriptRecord := ScriptRecord{Id: script.Id, Output: script.Output}
+			if len(script.Table)+len(script.Elem) != 0 {
+				scriptRecord.Data = script.Value()
+			}
+			record.Scripts = append(record.Scripts, scriptRecord)
+		}
+		records = append(records, record)
+	}
+	return records
+}
@@ -0,0 +1,67 @@
+package nmap
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWriteJSON(t *testing.T) {
+	result := loadXML(t, "testdata/scanme.xml")
+	var content bytes.Buffer
+	if err := WriteJSON(&content, result); err != nil {
+		t.Fatal(err)
+	}
+	var document JSONDocument
+	if err := json.Unmarshal(content.Bytes(), &document); err != nil {
+		t.Fatal(err)
+	}
+	result.XMLName = xml.Name{}
+	if document.Schema != JSONSchema || !reflect.DeepEqual(document.Result, result) {
+		t.Errorf("json round trip result differs")
+	}
+}
+
+func TestWriteJSONL(t *testing.T) {
+	result := loadXML(t, "testdata/scanme.xml")
+	cfg := NewConfig()
+	cfg.ResultName = filepath.Join(t.TempDir(), "Result")
+	if err := NewNmap(cfg).ExportJSONL(result); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(cfg.ResultName + ".jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var records []PortRecord
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		var record PortRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 7 {
+		t.Fatalf("expected 7 open ports, but got %d", len(records))
+	}
+	ssh := records[0]
+	if ssh.Schema != JSONLSchema || ssh.Address != "45.33.32.156" || ssh.Port != 22 || ssh.Protocol != "tcp" || ssh.Product != "OpenSSH" ||
+		ssh.Confidence != 10 || len(ssh.CPE) != 2 || len(ssh.Scripts) != 2 || !ssh.ScanStart.Equal(time.Unix(result.Start, 0)) {
+		t.Errorf("unexpected record %+v", ssh)
+	}
+	if data, ok := ssh.Scripts[0].Data.([]interface{}); !ok || len(data) != 4 || ssh.Scripts[1].Data != nil {
+		t.Errorf("unexpected script data %v", ssh.Scripts)
+	}
+	if smb := records[6]; smb.Address != "192.168.1.10" || smb.MAC != "00:0C:29:3E:5A:11" || smb.Port != 445 || len(smb.Scripts) != 0 {
+		t.Errorf("unexpected record %+v", smb)
+	}
+}
@@ -0,0 +1,126 @@
+package nmap
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// 内置的文本模板名称
+const (
+	//魔方资产格式，每个地址一行，如 1.1.1.1[[80,tcp,open,http,nginx1.18.0],[443,tcp,open,https,null]]
+	TextMofang = "mofang"
+	//类似nmap -oG的格式，每个host一行Status，有端口时再输出一行Ports
+	TextGrepable = "grepable"
+	//每个开放端口一行，如 1.1.1.1:80，ipv6为 [::1]:80
+	TextHostPort = "hostport"
+	//类似masscan -oL的格式，如 open tcp 80 1.1.1.1 1650000000
+	TextMasscan = "masscan"
+)
+
+var textTemplates = map[string]string{
+	TextMofang: `{{range .Hosts}}{{$host := .}}{{range .Address}}{{.Addr}}` +
+		`{{with $host.PortList}}[{{range $i, $port := .}}{{if $i}},{{end}}` +
+		`[{{.PortId}},{{.Protocol}},{{.State.State}},{{.Service.Name}},{{with .Service.Product}}{{.}}{{$port.Service.Version}}{{else}}null{{end}}]` +
+		`{{end}}]{{end}}
+{{end}}{{end}}`,
+	TextGrepable: `{{range .Hosts}}{{$host := .}}Host: {{.IP}} ({{with .Names}}{{index . 0}}{{end}})	Status: {{title .Status.State}}
+{{with .PortList}}Host: {{$host.IP}} ({{with $host.Names}}{{index . 0}}{{end}})	Ports: ` +
+		`{{range $i, $port := .}}{{if $i}}, {{end}}{{.PortId}}/{{.State.State}}/{{.Protocol}}/{{.Owner.Name}}/{{grepable .Service.Name}}//{{with .Service}}{{grepable .Product}}{{with .Version}} {{grepable .}}{{end}}{{with .ExtraInfo}} ({{grepable .}}){{end}}{{end}}/{{end}}
+{{end}}{{end}}`,
+	TextHostPort: `{{range .OpenPorts}}{{hostport .Host.IP .Port.PortId}}
+{{end}}`,
+	TextMasscan: `#masscan
+{{range .OpenPorts}}{{.Port.State.State}} {{.Port.Protocol}} {{.Port.PortId}} {{.Host.IP}} {{or .Host.StartTime $.Result.Start}}
+{{end}}# end
+`,
+}
+
+// textFuncs 模板中可用的函数
+var textFuncs = template.FuncMap{
+	"join": strings.Join,
+	//product version extrainfo，以空格分隔
+	"version": serviceVersion,
+	//ip和端口，ipv6加方括号
+	"hostport": func(host string, port uint16) string {
+		return net.JoinHostPort(host, strconv.Itoa(int(port)))
+	},
+	//首字母大写，如 up 输出为 Up
+	"title": func(value any) string {
+		s := fmt.Sprint(value)
+		if s == "" {
+			return s
+		}
+		return strings.ToUpper(s[:1]) + s[1:]
+	},
+	//替换grepable格式中的分隔符
+	"grepable": strings.NewReplacer("/", "|", ",", "|").Replace,
+}
+
+// TextData 文本模板的数据
+//
+// 如每个host一行ip和开放端口：{{range .Hosts}}{{.IP}}{{range .OpenPorts}} {{.PortId}}/{{.Protocol}}{{end}}
+// {{end}}
+type TextData struct {
+	Result *NmapXMLResult
+	//所有host
+	Hosts HostSet
+	//所有开放端口
+	OpenPorts []HostPort
+}
+
+// TextExporter 通过text/template导出文本，模板的数据为TextData
+type TextExporter struct {
+	Template *template.Template
+}
+
+// NewTextExporter 使用内置的模板名称（TextMofang等）或模板内容创建TextExporter，
+// 模板中可使用join、version、hostport、title、grepable函数
+func NewTextExporter(text string) (*TextExporter, error) {
+	name := text
+	if builtin, ok := textTemplates[text]; ok {
+		text = builtin
+	} else {
+		name = "text"
+	}
+	tmpl, err := template.New(name).Funcs(textFuncs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return &TextExporter{Template: tmpl}, nil
+}
+
+func (e *TextExporter) Export(ctx context.Context, result *NmapXMLResult, w io.Writer) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(w)
+	data := TextData{
+		Result:    result,
+		Hosts:     result.Hosts(),
+		OpenPorts: result.OpenPorts(),
+	}
+	if err := e.Template.Execute(writer, data); err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+// WriteTxt 输出魔方资产格式，每个地址一行，如 1.1.1.1[[80,tcp,open,http,nginx1.18.0],[443,tcp,open,https,null]]
+func WriteTxt(w io.Writer, result *NmapXMLResult) error {
+	return WriteText(w, result, TextMofang)
+}
+
+// WriteText 按内置的模板名称或模板内容输出文本
+func WriteText(w io.Writer, result *NmapXMLResult, text string) error {
+	exporter, err := NewTextExporter(text)
+	if err != nil {
+		return err
+	}
+	return exporter.Export(context.Background(), result, w)
+}
@@ -0,0 +1,74 @@
+package nmap
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// legacyTxt 原ExportTxtResult的输出
+func legacyTxt(result *NmapXMLResult) string {
+	var builder strings.Builder
+	for _, host := range result.Hosts() {
+		for _, addr := range host.Address {
+			var outTotal []string
+			for _, port := range host.PortList() {
+				var version = port.Service.Product + port.Service.Version
+				if port.Service.Product == "" {
+					version = "null"
+				}
+				outTotal = append(outTotal, fmt.Sprintf("[%d,%s,%s,%s,%s]", port.PortId, port.Protocol, port.State.State, port.Service.Name, version))
+			}
+			var total = addr.Addr
+			if len(outTotal) != 0 {
+				total += "[" + strings.Join(outTotal, ",") + "]"
+			}
+			fmt.Fprintln(&builder, total)
+		}
+	}
+	return builder.String()
+}
+
+func TestWriteTxt(t *testing.T) {
+	for _, fileName := range xmlCorpus(t) {
+		result := loadXML(t, fileName)
+		var content bytes.Buffer
+		if err := WriteTxt(&content, result); err != nil {
+			t.Fatal(err)
+		}
+		if expected := legacyTxt(result); content.String() != expected {
+			t.Errorf("%s: expected\n%s\nbut got\n%s", fileName, expected, content.String())
+		}
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	result := loadXML(t, "testdata/scanme.xml")
+	cases := []struct {
+		text     string
+		contains []string
+	}{
+		{TextGrepable, []string{
+			"Host: 45.33.32.156 (scanme.nmap.org)\tStatus: Up\n",
+			"\tPorts: 22/open/tcp//ssh//OpenSSH 6.6.1p1 Ubuntu 2ubuntu2.13 (Ubuntu Linux; protocol 2.0)/, 80/open/tcp//http//",
+		}},
+		{TextHostPort, []string{"45.33.32.156:22\n", "192.168.1.10:445\n"}},
+		{TextMasscan, []string{"#masscan\n", "open tcp 80 45.33.32.156 ", "# end\n"}},
+		{`{{range .Hosts}}{{.IP}}{{range .OpenPorts}} {{.PortId}}{{end}}{{"\n"}}{{end}}`, []string{"45.33.32.156 22 80 443 9929\n"}},
+	}
+	for _, c := range cases {
+		var content bytes.Buffer
+		if err := WriteText(&content, result, c.text); err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range c.contains {
+			if !strings.Contains(content.String(), s) {
+				t.Errorf("%s: expected %q in\n%s", c.text, s, content.String())
+			}
+		}
+	}
+	if err := WriteText(&bytes.Buffer{}, result, "{{range}}"); err == nil {
+		t.Errorf("expected template parse error")
+	}
+}
@@ -0,0 +1,161 @@
+package nmap
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Exporter 将结果导出到io.Writer，可通过RegisterExporter注册新的格式
+type Exporter interface {
+	Export(ctx context.Context, result *NmapXMLResult, w io.Writer) error
+}
+
+// ExporterFunc 函数形式的Exporter
+type ExporterFunc func(ctx context.Context, result *NmapXMLResult, w io.Writer) error
+
+func (f ExporterFunc) Export(ctx context.Context, result *NmapXMLResult, w io.Writer) error {
+	return f(ctx, result, w)
+}
+
+// ExcelExporter 导出Excel，Option为导出选项，如 ExcelExporter{Option: *NewConfig()}
+type ExcelExporter struct {
+	Option config
+}
+
+func (e *ExcelExporter) Export(ctx context.Context, result *NmapXMLResult, w io.Writer) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return writeExcel(w, result, e.Option)
+}
+
+// CSVExporter 导出csv的host And Ports表
+type CSVExporter struct {
+	Option CSVOption
+}
+
+func (e *CSVExporter) Export(ctx context.Context, result *NmapXMLResult, w io.Writer) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return WriteCSV(w, result, e.Option)
+}
+
+var (
+	exportersMu sync.RWMutex
+	//格式名称同时作为文件扩展名
+	exporters = map[string]Exporter{
+		"xlsx":  &ExcelExporter{Option: *NewConfig()},
+		"csv":   &CSVExporter{},
+		"txt":   writerExporter(WriteTxt),
+		"xml":   writerExporter(WriteXML),
+		"json":  writerExporter(WriteJSON),
+		"jsonl": writerExporter(WriteJSONL),
+		"html":  writerExporter(WriteHTML),
+	}
+)
+
+// writerExporter 将WriteXXX包装为Exporter
+func writerExporter(write func(w io.Writer, result *NmapXMLResult) error) Exporter {
+	return ExporterFunc(func(ctx context.Context, result *NmapXMLResult, w io.Writer) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return write(w, result)
+	})
+}
+
+// RegisterExporter 注册导出格式，已存在时覆盖，format同时作为文件扩展名
+func RegisterExporter(format string, exporter Exporter) {
+	exportersMu.Lock()
+	defer exportersMu.Unlock()
+	exporters[format] = exporter
+}
+
+// LookupExporter 获取已注册的导出格式，不存在时返回ErrUnknownFormat
+func LookupExporter(format string) (Exporter, error) {
+	exportersMu.RLock()
+	defer exportersMu.RUnlock()
+	exporter, ok := exporters[format]
+	if !ok {
+		return nil, errors.Wrap(ErrUnknownFormat, format)
+	}
+	return exporter, nil
+}
+
+// ExportFormats 已注册的导出格式
+func ExportFormats() []string {
+	exportersMu.RLock()
+	defer exportersMu.RUnlock()
+	formats := make([]string, 0, len(exporters))
+	for format := range exporters {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// Export 按格式导出结果到w，xlsx、csv和txt使用当前的config
+func (receiver *nmap) Export(ctx context.Context, format string, result *NmapXMLResult, w io.Writer) error {
+	exporter, err := receiver.exporter(format)
+	if err != nil {
+		return err
+	}
+	return exporter.Export(ctx, result, w)
+}
+
+// ExportFile 按格式导出结果到Sink，名称为 ResultName.format，返回写入的位置
+func (receiver *nmap) ExportFile(ctx context.Context, format string, result *NmapXMLResult) (string, error) {
+	exporter, err := receiver.exporter(format)
+	if err != nil {
+		return "", err
+	}
+	return receiver.writeSink(receiver.exportOption.ResultName+"."+format, func(w io.Writer) error {
+		return exporter.Export(ctx, result, w)
+	})
+}
+
+func (receiver *nmap) exporter(format string) (Exporter, error) {
+	switch format {
+	case "xlsx":
+		return &ExcelExporter{Option: receiver.exportOption}, nil
+	case "csv":
+		return &CSVExporter{Option: receiver.csvOption()}, nil
+	case "txt":
+		if receiver.exportOption.TxtTemplate != "" {
+			return NewTextExporter(receiver.exportOption.TxtTemplate)
+		}
+	}
+	return LookupExporter(format)
+}
+
+// sink 未指定Sink时覆盖写入当前目录的文件
+func (receiver *nmap) sink() Sink {
+	if receiver.Sink == nil {
+		return FileSink{}
+	}
+	return receiver.Sink
+}
+
+func (receiver *nmap) writeSink(name string, write func(w io.Writer) error) (string, error) {
+	return writeSink(receiver.sink(), name, write)
+}
+
+// WriteXML 输出nmap格式的xml结果
+func WriteXML(w io.Writer, result *NmapXMLResult) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(result); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,128 @@
+package nmap
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestExporters(t *testing.T) {
+	result := loadXML(t, "testdata/scanme.xml")
+	for _, format := range ExportFormats() {
+		exporter, err := LookupExporter(format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var content bytes.Buffer
+		if err := exporter.Export(context.Background(), result, &content); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if content.Len() == 0 {
+			t.Errorf("%s: empty output", format)
+		}
+	}
+	if _, err := LookupExporter("pdf"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("expected ErrUnknownFormat, but got %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	exporter, _ := LookupExporter("txt")
+	if err := exporter.Export(ctx, result, &bytes.Buffer{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, but got %v", err)
+	}
+}
+
+func TestWriteXML(t *testing.T) {
+	result := loadXML(t, "testdata/scanme.xml")
+	var content bytes.Buffer
+	if err := WriteXML(&content, result); err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseXML(content.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed.OpenPorts()) != 7 || parsed.Args != result.Args {
+		t.Errorf("xml round trip result differs")
+	}
+}
+
+func TestExportSink(t *testing.T) {
+	result := loadXML(t, "testdata/scanme.xml")
+	sink := MemorySink{}
+	n := NewNmap()
+	n.Sink = sink
+	if err := n.ExportResult(result); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.ExportTxtResult(result); err != nil {
+		t.Fatal(err)
+	}
+	file, err := excelize.OpenReader(sink["Result.xlsx"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index := file.GetSheetIndex("host And Ports"); index == -1 {
+		t.Errorf("sheet host And Ports not found")
+	}
+	txt := sink["Result.txt"].String()
+	if !strings.HasPrefix(txt, "45.33.32.156[[22,tcp,open,ssh,OpenSSH") {
+		t.Errorf("unexpected txt output %q", txt)
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	dir := t.TempDir()
+	write := func(sink Sink, content string) (string, error) {
+		return writeSink(sink, "Result.txt", func(w io.Writer) error {
+			_, err := io.WriteString(w, content)
+			return err
+		})
+	}
+	read := func(target string) string {
+		content, err := os.ReadFile(target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(content)
+	}
+	target := filepath.Join(dir, "Result.txt")
+	for _, content := range []string{"first\n", "second\n"} {
+		if _, err := write(FileSink{Dir: dir}, content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if content := read(target); content != "second\n" {
+		t.Errorf("overwrite: unexpected content %q", content)
+	}
+	if _, err := write(FileSink{Dir: dir, Policy: Append}, "third\n"); err != nil {
+		t.Fatal(err)
+	}
+	if content := read(target); content != "second\nthird\n" {
+		t.Errorf("append: unexpected content %q", content)
+	}
+	if _, err := write(FileSink{Dir: dir, Policy: Exclusive}, "fourth\n"); !os.IsExist(err) {
+		t.Errorf("exclusive: expected file exists error, but got %v", err)
+	}
+	first, err := write(FileSink{Dir: dir, Policy: Timestamp}, "fifth\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := write(FileSink{Dir: dir, Policy: Timestamp}, "sixth\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == target || first == second || read(first) != "fifth\n" || read(second) != "sixth\n" {
+		t.Errorf("timestamp: unexpected targets %s %s", first, second)
+	}
+	if content := read(target); content != "second\nthird\n" {
+		t.Errorf("timestamp: existing file changed %q", content)
+	}
+}
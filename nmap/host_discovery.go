@@ -106,7 +106,8 @@ func (receiver *nmap) AddPn() *nmap {
 // until a timeout is reached, the host is marked as down.
 func (receiver *nmap) AddPS(ports ...string) *nmap {
 	portList := strings.Join(ports, ",")
-	return AddArgs(receiver, "-PS", portList)
+	//值只能紧跟参数，如-PS22,80
+	return AddArgs(receiver, "-PS"+portList)
 }
 
 // AddPA -PA [portlist]: TCP ACK discovery to given ports
@@ -120,7 +121,7 @@ func (receiver *nmap) AddPS(ports ...string) *nmap {
 // Another common type of firewall uses stateful rules that drop unexpected packets. This feature was initially found mostly on high-end firewalls, though it has become much more common over the years. The Linux Netfilter/iptables system supports this through the --state nmap, which categorizes packets based on connection state. A SYN probe is more likely to work against such a system, as unexpected ACK packets are generally recognized as bogus and dropped. A solution to this quandary is to send both SYN and ACK probes by specifying -PS and -PA.
 func (receiver *nmap) AddPA(portlist ...string) *nmap {
 	portList := strings.Join(portlist, ",")
-	return AddArgs(receiver, "-PA", portList)
+	return AddArgs(receiver, "-PA"+portList)
 }
 
 // AddPU -PU [portlist]:UDP discovery to given ports
@@ -135,7 +136,7 @@ func (receiver *nmap) AddPA(portlist ...string) *nmap {
 // The primary advantage of this scan type is that it bypasses firewalls and filters that only screen TCP. For example, I once owned a Linksys BEFW11S4 wireless broadband router. The external interface of this device filtered all TCP ports by default, but UDP probes would still elicit port unreachable messages and thus give away the device.
 func (receiver *nmap) AddPU(portlist ...string) *nmap {
 	portList := strings.Join(portlist, ",")
-	return AddArgs(receiver, "-PU", portList)
+	return AddArgs(receiver, "-PU"+portList)
 }
 
 // AddPY -PY [portlist]: SCTP discovery to given ports
@@ -150,7 +151,7 @@ func (receiver *nmap) AddPU(portlist ...string) *nmap {
 // On Unix boxes, only the privileged user root is generally able to send and receive raw SCTP packets. Using SCTP INIT Pings is currently not possible for unprivileged users.
 func (receiver *nmap) AddPY(portlist ...string) *nmap {
 	portList := strings.Join(portlist, ",")
-	return AddArgs(receiver, "-PY", portList)
+	return AddArgs(receiver, "-PY"+portList)
 }
 
 // AddPE -PE/PP/PM: ICMP echo, timestamp, and netmask request discovery probes
@@ -187,7 +188,7 @@ func (receiver *nmap) AddPM() *nmap {
 // This host discovery method looks for either responses using the same protocol as a probe, or ICMP protocol unreachable messages which signify that the given protocol isn't supported on the destination host. Either type of response signifies that the target host is alive.
 func (receiver *nmap) AddPO(protocollist ...string) *nmap {
 	protocolList := strings.Join(protocollist, ",")
-	return AddArgs(receiver, "-PO", protocolList)
+	return AddArgs(receiver, "-PO"+protocolList)
 }
 
 // Adddisablearpping --disable-arp-ping (No ARP or ND Ping)
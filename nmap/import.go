@@ -0,0 +1,458 @@
+package nmap
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// importer 按ip汇总端口，生成NmapXMLResult
+type importer struct {
+	result *NmapXMLResult
+	//ip在result.Host中的位置
+	hosts map[string]int
+	//端口在host中的位置
+	ports map[PortKey]int
+}
+
+func newImporter(scanner string) *importer {
+	return &importer{result: &NmapXMLResult{Scanner: scanner}, hosts: make(map[string]int), ports: make(map[PortKey]int)}
+}
+
+// add 增加端口，同一ip的同一端口只保留一个，banner作为脚本输出
+func (im *importer) add(ip, hostname string, port Port, timestamp int64) {
+	i, ok := im.hosts[ip]
+	if !ok {
+		i = len(im.result.Host)
+		im.hosts[ip] = i
+		host := Host{Status: Status{State: HostStateUp}, Address: []Address{hostAddress(ip)}, Ports: []Ports{{}}}
+		im.result.Host = append(im.result.Host, host)
+	}
+	host := &im.result.Host[i]
+	if hostname != "" && hostname != ip && !contains(host.Names(), hostname) {
+		host.Hostnames = append(host.Hostnames, Hostname{Name: hostname, Type: HostnameTypeUser})
+	}
+	if timestamp != 0 {
+		if host.StartTime == 0 || timestamp < host.StartTime {
+			host.StartTime = timestamp
+		}
+		if timestamp > host.EndTime {
+			host.EndTime = timestamp
+		}
+	}
+	if port.State.State == "" {
+		port.State.State = "open"
+	}
+	key := PortKey{Address: ip, Protocol: port.Protocol, PortId: port.PortId}
+	if j, ok := im.ports[key]; ok {
+		existing := &host.Ports[0].Port[j]
+		existing.Script = append(existing.Script, port.Script...)
+		if existing.State.Reason == "" {
+			existing.State = port.State
+		}
+		return
+	}
+	im.ports[key] = len(host.Ports[0].Port)
+	host.Ports[0].Port = append(host.Ports[0].Port, port)
+}
+
+// finish 端口按协议和端口号排序，生成开始时间和runstats
+func (im *importer) finish() *NmapXMLResult {
+	result := im.result
+	var start, end int64
+	for i := range result.Host {
+		host := &result.Host[i]
+		ports := host.Ports[0].Port
+		sort.SliceStable(ports, func(i, j int) bool {
+			if ports[i].Protocol != ports[j].Protocol {
+				return ports[i].Protocol < ports[j].Protocol
+			}
+			return ports[i].PortId < ports[j].PortId
+		})
+		if host.StartTime != 0 && (start == 0 || host.StartTime < start) {
+			start = host.StartTime
+		}
+		if host.EndTime > end {
+			end = host.EndTime
+		}
+	}
+	if result.Start == 0 {
+		result.Start = start
+	}
+	if result.Start != 0 && result.StartStr == "" {
+		result.StartStr = time.Unix(result.Start, 0).Format(outputTimeLayouts[0])
+	}
+	finished := &result.RunStats.Finished
+	if finished.Time == 0 {
+		finished.Time = end
+	}
+	if finished.Time != 0 && finished.TimeStr == "" {
+		finished.TimeStr = time.Unix(finished.Time, 0).Format(outputTimeLayouts[0])
+	}
+	if finished.Exit == "" {
+		finished.Exit = "success"
+	}
+	up := len(result.Host)
+	result.RunStats.Hosts = Hosts{Up: up, Total: up}
+	finished.Summary = result.Scanner + " import; " + strconv.Itoa(up) + " hosts with open ports"
+	return result
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// masscan -oX的结果，格式与nmap相同，banner在service的banner属性中
+type masscanXML struct {
+	Start    int64      `xml:"start,attr"`
+	Version  string     `xml:"version,attr"`
+	ScanInfo []ScanInfo `xml:"scaninfo"`
+	Host     []struct {
+		EndTime int64     `xml:"endtime,attr"`
+		Address []Address `xml:"address"`
+		Port    []struct {
+			Protocol PortProtocol `xml:"protocol,attr"`
+			PortId   uint16       `xml:"portid,attr"`
+			State    State        `xml:"state"`
+			Service  struct {
+				Name   string `xml:"name,attr"`
+				Banner string `xml:"banner,attr"`
+			} `xml:"service"`
+		} `xml:"ports>port"`
+	} `xml:"host"`
+	Finished Finished `xml:"runstats>finished"`
+}
+
+// masscan -oJ的一条记录
+type masscanRecord struct {
+	IP        string `json:"ip"`
+	Timestamp string `json:"timestamp"`
+	Ports     []struct {
+		Port    uint16 `json:"port"`
+		Proto   string `json:"proto"`
+		Status  string `json:"status"`
+		Reason  string `json:"reason"`
+		TTL     int    `json:"ttl"`
+		Service *struct {
+			Name   string `json:"name"`
+			Banner string `json:"banner"`
+		} `json:"service"`
+	} `json:"ports"`
+}
+
+// masscanBanner masscan的banner作为脚本输出，脚本id为masscan-服务名，如masscan-http、masscan-title
+func masscanBanner(name, banner string) Script {
+	return Script{Id: "masscan-" + name, Output: banner}
+}
+
+// ImportMasscan 导入masscan的结果，支持-oX、-oJ（包括ndjson）和-oL，格式根据内容判断
+//
+// 每个ip为一个host，banner作为脚本输出，脚本id为masscan-服务名
+func ImportMasscan(data []byte) (*NmapXMLResult, error) {
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		return importMasscanXML(data)
+	case bytes.HasPrefix(trimmed, []byte("[")), bytes.HasPrefix(trimmed, []byte("{")):
+		return importMasscanJSON(trimmed)
+	}
+	return importMasscanList(data)
+}
+
+func importMasscanXML(data []byte) (*NmapXMLResult, error) {
+	var run masscanXML
+	if err := xml.Unmarshal(data, &run); err != nil {
+		return nil, &XMLParseError{Err: err}
+	}
+	im := newImporter("masscan")
+	im.result.Version = run.Version
+	im.result.Start = run.Start
+	im.result.ScanInfo = run.ScanInfo
+	for _, host := range run.Host {
+		for _, addr := range host.Address {
+			if addr.AddrType == "mac" {
+				continue
+			}
+			for _, p := range host.Port {
+				port := Port{Protocol: p.Protocol, PortId: p.PortId, State: p.State}
+				if p.Service.Banner != "" {
+					port.Script = []Script{masscanBanner(p.Service.Name, p.Service.Banner)}
+				}
+				im.add(addr.Addr, "", port, host.EndTime)
+			}
+		}
+	}
+	result := im.finish()
+	if run.Finished.Time != 0 {
+		result.RunStats.Finished.Time = run.Finished.Time
+		result.RunStats.Finished.TimeStr = run.Finished.TimeStr
+		result.RunStats.Finished.Elapsed = run.Finished.Elapsed
+	}
+	return result, nil
+}
+
+// importMasscanJSON 逐个解析json对象，兼容旧版本masscan输出的多余逗号
+func importMasscanJSON(data []byte) (*NmapXMLResult, error) {
+	im := newImporter("masscan")
+	rest := bytes.TrimPrefix(data, []byte("["))
+	for {
+		rest = bytes.TrimLeft(rest, " \t\r\n,")
+		//旧版本最后一条为 {finished: 1}，不是合法的json
+		if len(rest) == 0 || rest[0] == ']' || bytes.HasPrefix(rest, []byte("{finished")) {
+			break
+		}
+		decoder := json.NewDecoder(bytes.NewReader(rest))
+		var record masscanRecord
+		if err := decoder.Decode(&record); err != nil {
+			return nil, errors.Wrap(ErrOutputParse, "masscan json: "+err.Error())
+		}
+		rest = rest[decoder.InputOffset():]
+		if record.IP == "" {
+			continue
+		}
+		timestamp, _ := strconv.ParseInt(record.Timestamp, 10, 64)
+		for _, p := range record.Ports {
+			port := Port{Protocol: PortProtocol(p.Proto), PortId: p.Port, State: State{State: PortState(p.Status), Reason: p.Reason, ReasonTTL: p.TTL}}
+			if p.Service != nil {
+				port.Script = []Script{masscanBanner(p.Service.Name, p.Service.Banner)}
+			}
+			im.add(record.IP, "", port, timestamp)
+		}
+	}
+	return im.finish(), nil
+}
+
+// importMasscanList 解析-oL，如 open tcp 80 1.1.1.1 1650247201，banner tcp 80 1.1.1.1 1650247201 http Apache
+func importMasscanList(data []byte) (*NmapXMLResult, error) {
+	im := newImporter("masscan")
+	scanner := newLineScanner(data)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.SplitN(line, " ", 7)
+		if len(fields) < 5 {
+			return nil, errors.Wrapf(ErrOutputParse, "masscan list line %d: %q", lineNum, line)
+		}
+		id, err := strconv.ParseUint(fields[2], 10, 16)
+		if err != nil {
+			return nil, errors.Wrapf(ErrOutputParse, "masscan list line %d: %q", lineNum, line)
+		}
+		timestamp, _ := strconv.ParseInt(fields[4], 10, 64)
+		port := Port{Protocol: PortProtocol(fields[1]), PortId: uint16(id)}
+		if fields[0] == "banner" {
+			if len(fields) < 7 {
+				continue
+			}
+			port.Script = []Script{masscanBanner(fields[5], fields[6])}
+		} else {
+			port.State.State = PortState(fields[0])
+		}
+		im.add(fields[3], "", port, timestamp)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return im.finish(), nil
+}
+
+// naabu -json的一条记录，旧版本没有protocol，port可能为对象
+type naabuRecord struct {
+	Host      string          `json:"host"`
+	IP        string          `json:"ip"`
+	Port      json.RawMessage `json:"port"`
+	Protocol  string          `json:"protocol"`
+	TLS       bool            `json:"tls"`
+	Timestamp time.Time       `json:"timestamp"`
+}
+
+// ImportNaabu 导入naabu -json的结果，每行一个开放端口
+func ImportNaabu(data []byte) (*NmapXMLResult, error) {
+	im := newImporter("naabu")
+	scanner := newLineScanner(data)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var record naabuRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			return nil, errors.Wrapf(ErrOutputParse, "naabu line %d: %s", lineNum, err)
+		}
+		port := Port{Protocol: PortProtocol(strings.ToLower(record.Protocol))}
+		var object struct {
+			Port     uint16
+			Protocol string
+			TLS      bool
+		}
+		if err := json.Unmarshal(record.Port, &port.PortId); err != nil {
+			if err := json.Unmarshal(record.Port, &object); err != nil {
+				return nil, errors.Wrapf(ErrOutputParse, "naabu line %d: invalid port %s", lineNum, record.Port)
+			}
+			port.PortId = object.Port
+			if port.Protocol == "" {
+				port.Protocol = PortProtocol(strings.ToLower(object.Protocol))
+			}
+			record.TLS = record.TLS || object.TLS
+		}
+		if port.Protocol == "" {
+			port.Protocol = PortProtocolTcp
+		}
+		if record.TLS {
+			port.Service.Tunnel = "ssl"
+		}
+		ip := record.IP
+		if ip == "" {
+			ip = record.Host
+		}
+		var timestamp int64
+		if !record.Timestamp.IsZero() {
+			timestamp = record.Timestamp.Unix()
+		}
+		im.add(ip, record.Host, port, timestamp)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return im.finish(), nil
+}
+
+var (
+	//RustScan -g的结果，如 1.1.1.1 -> [22,80]
+	rustScanGreppableRegexp = regexp.MustCompile(`^(\S+) -> \[([\d,\s]*)\]$`)
+	//RustScan的默认输出，如 Open 1.1.1.1:80
+	rustScanOpenRegexp = regexp.MustCompile(`^Open (\S+):(\d+)$`)
+)
+
+// ImportRustScan 导入RustScan的结果，支持-g的 ip -> [ports] 和默认输出中的 Open ip:port，其他行忽略
+func ImportRustScan(data []byte) (*NmapXMLResult, error) {
+	im := newImporter("rustscan")
+	scanner := newLineScanner(data)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if m := rustScanGreppableRegexp.FindStringSubmatch(line); m != nil {
+			for _, value := range strings.Split(m[2], ",") {
+				id, err := strconv.ParseUint(strings.TrimSpace(value), 10, 16)
+				if err != nil {
+					continue
+				}
+				im.add(m[1], "", Port{Protocol: PortProtocolTcp, PortId: uint16(id)}, 0)
+			}
+			continue
+		}
+		if m := rustScanOpenRegexp.FindStringSubmatch(line); m != nil {
+			id, err := strconv.ParseUint(m[2], 10, 16)
+			if err != nil {
+				continue
+			}
+			im.add(strings.Trim(m[1], "[]"), "", Port{Protocol: PortProtocolTcp, PortId: uint16(id)}, 0)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return im.finish(), nil
+}
+
+// FollowUp 根据导入的结果生成针对开放端口的nmap扫描，开放端口相同的host合并为一个扫描，
+// 如 nmap -Pn -p T:22,80 1.1.1.1 2.2.2.2，有udp端口时增加-sS -sU，可继续增加-sV等参数
+//
+// cfg为每个扫描的导出配置，与NewNmap相同
+func FollowUp(result *NmapXMLResult, cfg ...*config) []*nmap {
+	groups := followUpGroups(result, false, 0)
+	scans := make([]*nmap, 0, len(groups))
+	for _, g := range groups {
+		scans = append(scans, g.apply(NewNmap(cfg...)))
+	}
+	return scans
+}
+
+// followUpGroup 开放端口相同的一组host
+type followUpGroup struct {
+	//-p的值，如 T:22,80,U:161，为空时使用nmap默认端口
+	ports string
+	tcp   bool
+	udp   bool
+	//IPv6的host，需要-6
+	ipv6    bool
+	targets []string
+}
+
+// followUpGroups 存活的host按开放端口分组，batch大于0时每组最多batch个host
+//
+// withoutPorts为true时没有开放端口的host也作为一组（如-sn的结果），否则忽略
+func followUpGroups(result *NmapXMLResult, withoutPorts bool, batch int) []*followUpGroup {
+	var groups []*followUpGroup
+	byPorts := make(map[string]*followUpGroup)
+	for _, host := range result.Hosts().Up() {
+		var tcp, udp []string
+		for _, port := range host.OpenPorts() {
+			switch port.Protocol {
+			case PortProtocolTcp:
+				tcp = append(tcp, strconv.Itoa(int(port.PortId)))
+			case PortProtocolUdpProto:
+				udp = append(udp, strconv.Itoa(int(port.PortId)))
+			}
+		}
+		if len(tcp) == 0 && len(udp) == 0 && !withoutPorts {
+			continue
+		}
+		var ports []string
+		if len(tcp) != 0 {
+			ports = append(ports, "T:"+strings.Join(tcp, ","))
+		}
+		if len(udp) != 0 {
+			ports = append(ports, "U:"+strings.Join(udp, ","))
+		}
+		//IPv4和IPv6的host不能在同一次扫描中
+		ipv6 := strings.Contains(host.IP(), ":")
+		key := strings.Join(ports, ",")
+		g, ok := byPorts[strconv.FormatBool(ipv6)+key]
+		if !ok || batch > 0 && len(g.targets) >= batch {
+			g = &followUpGroup{ports: key, tcp: len(tcp) != 0, udp: len(udp) != 0, ipv6: ipv6}
+			byPorts[strconv.FormatBool(ipv6)+key] = g
+			groups = append(groups, g)
+		}
+		g.targets = append(g.targets, host.IP())
+	}
+	return groups
+}
+
+// apply 增加-Pn、端口和目标，有udp端口时增加-sU，同时有tcp端口且没有TCP扫描方式时增加-sS，IPv6的host增加-6
+func (g *followUpGroup) apply(n *nmap) *nmap {
+	if !hasArg(n.Args, "-Pn") {
+		n.AddPn()
+	}
+	if g.ipv6 && !hasArg(n.Args, "-6") {
+		n.Add6()
+	}
+	if g.udp {
+		if g.tcp && !hasAnyArg(n.Args, tcpScanTypes...) {
+			n.AddsS()
+		}
+		if !hasArg(n.Args, "-sU") {
+			n.AddsU()
+		}
+	}
+	if g.ports != "" {
+		n.Addp(g.ports)
+	}
+	return n.AddTargets(g.targets...)
+}
@@ -0,0 +1,79 @@
+package nmap
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestImport(t *testing.T) {
+	cases := []struct {
+		fileName string
+		importer func([]byte) (*NmapXMLResult, error)
+		ports    []string
+	}{
+		{"testdata/masscan.xml", ImportMasscan, []string{"45.33.32.156:22/tcp", "45.33.32.156:80/tcp", "192.168.1.10:445/tcp", "192.168.1.10:161/udp"}},
+		{"testdata/masscan.json", ImportMasscan, []string{"45.33.32.156:22/tcp", "45.33.32.156:80/tcp", "192.168.1.10:445/tcp", "192.168.1.10:161/udp"}},
+		{"testdata/masscan.txt", ImportMasscan, []string{"45.33.32.156:22/tcp", "45.33.32.156:80/tcp", "192.168.1.10:445/tcp", "192.168.1.10:161/udp"}},
+		{"testdata/naabu.json", ImportNaabu, []string{"45.33.32.156:22/tcp", "45.33.32.156:80/tcp", "192.168.1.10:445/tcp", "192.168.1.10:161/udp"}},
+		{"testdata/rustscan.txt", ImportRustScan, []string{"45.33.32.156:22/tcp", "45.33.32.156:80/tcp", "192.168.1.10:445/tcp"}},
+	}
+	for _, c := range cases {
+		data, err := os.ReadFile(c.fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := c.importer(data)
+		if err != nil {
+			t.Fatalf("%s: %v", c.fileName, err)
+		}
+		var ports []string
+		for _, hostPort := range result.OpenPorts() {
+			ports = append(ports, fmt.Sprintf("%s:%d/%s", hostPort.Host.IP(), hostPort.Port.PortId, hostPort.Port.Protocol))
+		}
+		if !reflect.DeepEqual(ports, c.ports) || result.RunStats.Hosts.Up != 2 {
+			t.Errorf("%s: expected %v, but got %v", c.fileName, c.ports, ports)
+		}
+		if strings.HasPrefix(c.fileName, "testdata/masscan") {
+			ssh := result.Hosts().ByIP("45.33.32.156").FindPort(PortProtocolTcp, 22)
+			if script := ssh.FindScript("masscan-ssh"); script == nil || !strings.HasPrefix(script.Output, "SSH-2.0-OpenSSH") || ssh.State.State != "open" {
+				t.Errorf("%s: unexpected ssh port %+v", c.fileName, ssh)
+			}
+		}
+	}
+}
+
+func TestFollowUp(t *testing.T) {
+	data, err := os.ReadFile("testdata/masscan.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := ImportMasscan(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scans := FollowUp(result)
+	if len(scans) != 2 {
+		t.Fatalf("expected 2 scans, but got %d", len(scans))
+	}
+	expected := [][]string{
+		{"-Pn", "-p", "T:22,80", "45.33.32.156"},
+		{"-Pn", "-sS", "-sU", "-p", "T:445,U:161", "192.168.1.10"},
+	}
+	for i, scan := range scans {
+		if !reflect.DeepEqual(scan.Args, expected[i]) {
+			t.Errorf("expected %v, but got %v", expected[i], scan.Args)
+		}
+	}
+
+	//合并的扫描方式和别名不重复增加
+	g := &followUpGroup{ports: "T:445,U:161", tcp: true, udp: true, targets: []string{"192.168.1.10"}}
+	for _, args := range [][]string{{"-sSUV", "-P0"}, {"-sUV", "-sT", "-PN"}} {
+		want := append(append([]string(nil), args...), "-p", "T:445,U:161", "192.168.1.10")
+		if scan := g.apply(AddArgs(NewNmap(), args...)); !reflect.DeepEqual(scan.Args, want) {
+			t.Errorf("expected %v, but got %v", want, scan.Args)
+		}
+	}
+}
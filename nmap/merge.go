@@ -0,0 +1,528 @@
+package nmap
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PortKey 合并结果中端口的唯一标识
+type PortKey struct {
+	//ip地址，没有ip时为mac地址
+	Address  string       `json:"address"`
+	Protocol PortProtocol `json:"protocol"`
+	PortId   uint16       `json:"portid"`
+}
+
+// PortSource 端口状态和服务识别来自哪次扫描
+type PortSource struct {
+	//端口状态来自的扫描，Merge参数中的下标
+	Run   int    `json:"run"`
+	Args  string `json:"args"`
+	Start int64  `json:"start"`
+	//服务识别来自的扫描，保留了置信度更高的旧结果时与Run不同
+	ServiceRun   int    `json:"service_run"`
+	ServiceArgs  string `json:"service_args"`
+	ServiceStart int64  `json:"service_start"`
+}
+
+// Provenance 合并结果中每个端口的来源
+type Provenance map[PortKey]PortSource
+
+// Merge 合并多次扫描的结果，如拆分目标后的多次扫描、TCP和UDP分开扫描、-sS之后再-sV
+//
+// 按地址合并host，按开始时间从旧到新合并：
+//   - 端口状态以较新的扫描为准，服务识别以置信度（conf）高的为准，置信度相同时以较新的为准
+//   - 未显示的端口（ExtraPorts）按协议以较新的扫描为准，并减去其他扫描中列出的端口（需要nmap 7.80以上的extrareasons ports）
+//   - 脚本按id合并，操作系统识别按名称合并并保留较高的准确率，traceroute按协议和端口合并
+//   - 任一次扫描中存活的host即为存活
+//   - RunStats.Hosts根据合并后的host重新计算，未输出到xml的host（如未加-v时不存活的host）不计入
+func Merge(results ...*NmapXMLResult) (*NmapXMLResult, Provenance) {
+	runs := make([]int, 0, len(results))
+	for i, result := range results {
+		if result != nil {
+			runs = append(runs, i)
+		}
+	}
+	sort.SliceStable(runs, func(i, j int) bool {
+		return results[runs[i]].Start < results[runs[j]].Start
+	})
+
+	merged := &NmapXMLResult{}
+	provenance := make(Provenance)
+	hosts := make(map[string]*Host)
+	var order []string
+	ports := make(map[PortKey]Port)
+	var args, errorMsg []string
+	exit := "success"
+	for _, run := range runs {
+		result := results[run]
+		mergeRun(merged, result)
+		args = append(args, result.Args)
+		if result.RunStats.Finished.Exit != "" && result.RunStats.Finished.Exit != "success" {
+			exit = result.RunStats.Finished.Exit
+		}
+		if msg := result.RunStats.Finished.ErrorMsg; msg != "" {
+			errorMsg = append(errorMsg, msg)
+		}
+		for i := range result.Host {
+			host := &result.Host[i]
+			key := hostKey(host)
+			dst, ok := hosts[key]
+			if !ok {
+				dst = &Host{}
+				hosts[key] = dst
+				order = append(order, key)
+			}
+			mergeHost(dst, host)
+			for _, port := range host.PortList() {
+				portKey := PortKey{Address: key, Protocol: port.Protocol, PortId: port.PortId}
+				source := PortSource{Run: run, Args: result.Args, Start: result.Start,
+					ServiceRun: run, ServiceArgs: result.Args, ServiceStart: result.Start}
+				if existing, ok := ports[portKey]; ok {
+					var olderService bool
+					ports[portKey], olderService = mergePort(existing, *port)
+					if olderService {
+						older := provenance[portKey]
+						source.ServiceRun, source.ServiceArgs, source.ServiceStart = older.ServiceRun, older.ServiceArgs, older.ServiceStart
+					}
+				} else {
+					ports[portKey] = copyPort(*port)
+				}
+				provenance[portKey] = source
+			}
+		}
+	}
+
+	//端口按协议和端口号排序后放回host
+	keys := make([]PortKey, 0, len(ports))
+	for key := range ports {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Protocol != keys[j].Protocol {
+			return keys[i].Protocol < keys[j].Protocol
+		}
+		return keys[i].PortId < keys[j].PortId
+	})
+	for _, key := range keys {
+		host := hosts[key.Address]
+		if len(host.Ports) == 0 {
+			host.Ports = []Ports{{}}
+		}
+		host.Ports[0].Port = append(host.Ports[0].Port, ports[key])
+	}
+
+	var up, down int
+	for _, key := range order {
+		host := hosts[key]
+		if len(host.Ports) != 0 {
+			host.Ports[0].ExtraPorts = excludeListedPorts(host.Ports[0].ExtraPorts, host.Ports[0].Port)
+		}
+		merged.Host = append(merged.Host, *host)
+		switch host.Status.State {
+		case HostStateUp:
+			up++
+		case HostStateDown:
+			down++
+		}
+	}
+	merged.Args = strings.Join(args, " ; ")
+	merged.RunStats.Hosts = Hosts{Up: up, Down: down, Total: up + down}
+	finished := &merged.RunStats.Finished
+	finished.Exit = exit
+	finished.ErrorMsg = strings.Join(errorMsg, "; ")
+	if finished.Time != 0 {
+		finished.TimeStr = time.Unix(finished.Time, 0).Format(time.ANSIC)
+		if merged.Start != 0 {
+			finished.Elapsed = float32(finished.Time - merged.Start)
+		}
+	}
+	finished.Summary = fmt.Sprintf("Nmap done: %d runs merged; %d IP addresses (%d hosts up)", len(runs), up+down, up)
+	return merged, provenance
+}
+
+// mergeRun 合并扫描本身的信息，result比merged中已合并的扫描新
+func mergeRun(merged, result *NmapXMLResult) {
+	if merged.Start == 0 || (result.Start != 0 && result.Start < merged.Start) {
+		merged.Start, merged.StartStr = result.Start, result.StartStr
+	}
+	merged.XMLName = result.XMLName
+	merged.Scanner = result.Scanner
+	merged.Version = result.Version
+	merged.XMLOutputVersion = result.XMLOutputVersion
+	merged.Verbose = result.Verbose
+	merged.Debugging = result.Debugging
+	if result.ProfileName != "" {
+		merged.ProfileName = result.ProfileName
+	}
+	for _, scanInfo := range result.ScanInfo {
+		if !containsScanInfo(merged.ScanInfo, scanInfo) {
+			merged.ScanInfo = append(merged.ScanInfo, scanInfo)
+		}
+	}
+	merged.Target = append(merged.Target, result.Target...)
+	merged.TaskBegin = append(merged.TaskBegin, result.TaskBegin...)
+	merged.TaskProgress = append(merged.TaskProgress, result.TaskProgress...)
+	merged.TaskEnd = append(merged.TaskEnd, result.TaskEnd...)
+	for _, hostHint := range result.HostHint {
+		if !containsHostHint(merged.HostHint, hostHint) {
+			merged.HostHint = append(merged.HostHint, hostHint)
+		}
+	}
+	merged.Prescript = mergeScripts(merged.Prescript, result.Prescript)
+	merged.Postscript = mergeScripts(merged.Postscript, result.Postscript)
+	merged.Output = append(merged.Output, result.Output...)
+	if result.RunStats.Finished.Time > merged.RunStats.Finished.Time {
+		merged.RunStats.Finished.Time = result.RunStats.Finished.Time
+	}
+}
+
+func containsHostHint(hostHints []HostHint, hostHint HostHint) bool {
+	for _, h := range hostHints {
+		if reflect.DeepEqual(h, hostHint) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsScanInfo(scanInfos []ScanInfo, scanInfo ScanInfo) bool {
+	for _, s := range scanInfos {
+		if s.Type == scanInfo.Type && s.Protocol == scanInfo.Protocol {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeHost 将src合并到dst，src比dst新，端口由Merge单独合并
+func mergeHost(dst, src *Host) {
+	if dst.StartTime == 0 || (src.StartTime != 0 && src.StartTime < dst.StartTime) {
+		dst.StartTime = src.StartTime
+	}
+	if src.EndTime > dst.EndTime {
+		dst.EndTime = src.EndTime
+	}
+	dst.TimedOut = dst.TimedOut || src.TimedOut
+	if src.Comment != "" {
+		dst.Comment = src.Comment
+	}
+	if dst.Status.State != HostStateUp || src.Status.State == HostStateUp {
+		dst.Status = src.Status
+	}
+	for _, addr := range src.Address {
+		if !containsAddr(dst.Address, addr.Addr) {
+			dst.Address = append(dst.Address, addr)
+		}
+	}
+	for _, hostname := range src.Hostnames {
+		if !containsHostname(dst.Hostnames, hostname) {
+			dst.Hostnames = append(dst.Hostnames, hostname)
+		}
+	}
+	dst.Smurf = append(dst.Smurf, src.Smurf...)
+	for _, ports := range src.Ports {
+		if len(ports.ExtraPorts) == 0 {
+			continue
+		}
+		if len(dst.Ports) == 0 {
+			dst.Ports = []Ports{{}}
+		}
+		dst.Ports[0].ExtraPorts = mergeExtraPorts(dst.Ports[0].ExtraPorts, ports.ExtraPorts)
+	}
+	if len(src.OS) != 0 {
+		dst.OS = []OS{mergeOS(dst.OS, src.OS)}
+	}
+	if len(src.Distance) != 0 {
+		dst.Distance = src.Distance
+	}
+	if len(src.Uptime) != 0 {
+		dst.Uptime = src.Uptime
+	}
+	if len(src.TCPSequence) != 0 {
+		dst.TCPSequence = src.TCPSequence
+	}
+	if len(src.IpIdSequence) != 0 {
+		dst.IpIdSequence = src.IpIdSequence
+	}
+	if len(src.TCPTSSequence) != 0 {
+		dst.TCPTSSequence = src.TCPTSSequence
+	}
+	dst.HostScript = mergeScripts(dst.HostScript, src.HostScript)
+	dst.Trace = mergeTraces(dst.Trace, src.Trace)
+	if src.Times != (Times{}) {
+		dst.Times = src.Times
+	}
+}
+
+func containsAddr(addrs []Address, addr string) bool {
+	for _, a := range addrs {
+		if strings.EqualFold(a.Addr, addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsHostname(hostnames []Hostname, hostname Hostname) bool {
+	for _, h := range hostnames {
+		if h == hostname {
+			return true
+		}
+	}
+	return false
+}
+
+// mergePort newer比older新，状态以newer为准，服务以置信度高的为准，olderService为true时服务来自older
+func mergePort(older, newer Port) (port Port, olderService bool) {
+	port = copyPort(newer)
+	if older.Service.Conf > newer.Service.Conf {
+		port.Service = older.Service
+		olderService = true
+	}
+	port.Script = mergeScripts(older.Script, newer.Script)
+	return port, olderService
+}
+
+// mergeExtraPorts 按协议合并未显示的端口，newer中有的协议替换older中的，
+// 没有协议（旧版本nmap的extrareasons没有proto）时全部替换
+func mergeExtraPorts(older, newer []ExtraPorts) []ExtraPorts {
+	protocols := make(map[PortProtocol]bool)
+	var merged []ExtraPorts
+	for _, extraPorts := range newer {
+		for _, split := range splitExtraPorts(extraPorts) {
+			protocols[extraPortsProtocol(split)] = true
+			merged = append(merged, split)
+		}
+	}
+	if protocols[""] {
+		return merged
+	}
+	var kept []ExtraPorts
+	for _, extraPorts := range older {
+		for _, split := range splitExtraPorts(extraPorts) {
+			if protocol := extraPortsProtocol(split); protocol == "" || !protocols[protocol] {
+				kept = append(kept, split)
+			}
+		}
+	}
+	return append(kept, merged...)
+}
+
+// splitExtraPorts 按协议拆分，如-sS -sU时closed的tcp和udp端口在同一个extraports中
+func splitExtraPorts(extraPorts ExtraPorts) []ExtraPorts {
+	var split []ExtraPorts
+	for _, reason := range extraPorts.ExtraReasons {
+		if reason.Proto == "" {
+			return []ExtraPorts{copyExtraPorts(extraPorts)}
+		}
+		found := false
+		for i := range split {
+			if split[i].ExtraReasons[0].Proto == reason.Proto {
+				split[i].Count += reason.Count
+				split[i].ExtraReasons = append(split[i].ExtraReasons, reason)
+				found = true
+				break
+			}
+		}
+		if !found {
+			split = append(split, ExtraPorts{State: extraPorts.State, Count: reason.Count, ExtraReasons: []ExtraReasons{reason}})
+		}
+	}
+	if len(split) <= 1 {
+		return []ExtraPorts{copyExtraPorts(extraPorts)}
+	}
+	return split
+}
+
+func copyExtraPorts(extraPorts ExtraPorts) ExtraPorts {
+	extraPorts.ExtraReasons = append([]ExtraReasons(nil), extraPorts.ExtraReasons...)
+	return extraPorts
+}
+
+// extraPortsProtocol 拆分后的协议，没有时为空
+func extraPortsProtocol(extraPorts ExtraPorts) PortProtocol {
+	if len(extraPorts.ExtraReasons) == 0 {
+		return ""
+	}
+	return extraPorts.ExtraReasons[0].Proto
+}
+
+// excludeListedPorts 从extrareasons的ports中去掉已列出的端口，并减少数量
+func excludeListedPorts(extraPorts []ExtraPorts, ports []Port) []ExtraPorts {
+	var result []ExtraPorts
+	for _, e := range extraPorts {
+		e = copyExtraPorts(e)
+		for i := range e.ExtraReasons {
+			reason := &e.ExtraReasons[i]
+			if reason.Ports == "" || reason.Proto == "" {
+				continue
+			}
+			ranges := parsePortRanges(reason.Ports)
+			removed := 0
+			for _, port := range ports {
+				if port.Protocol == reason.Proto && removePort(&ranges, int(port.PortId)) {
+					removed++
+				}
+			}
+			if removed != 0 {
+				reason.Ports = formatPortRanges(ranges)
+				reason.Count -= removed
+				e.Count -= removed
+			}
+		}
+		if e.Count > 0 {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// parsePortRanges 解析extrareasons的ports，如 1,3-4,6-7
+func parsePortRanges(ports string) [][2]int {
+	var ranges [][2]int
+	for _, item := range strings.Split(ports, ",") {
+		from, to, isRange := strings.Cut(item, "-")
+		lo, err1 := strconv.Atoi(from)
+		hi, err2 := lo, error(nil)
+		if isRange {
+			hi, err2 = strconv.Atoi(to)
+		}
+		if err1 == nil && err2 == nil {
+			ranges = append(ranges, [2]int{lo, hi})
+		}
+	}
+	return ranges
+}
+
+// removePort 从范围中删除端口，端口不在范围中时返回false
+func removePort(ranges *[][2]int, port int) bool {
+	for i, r := range *ranges {
+		if port < r[0] || port > r[1] {
+			continue
+		}
+		var parts [][2]int
+		if port > r[0] {
+			parts = append(parts, [2]int{r[0], port - 1})
+		}
+		if port < r[1] {
+			parts = append(parts, [2]int{port + 1, r[1]})
+		}
+		*ranges = append((*ranges)[:i], append(parts, (*ranges)[i+1:]...)...)
+		return true
+	}
+	return false
+}
+
+func formatPortRanges(ranges [][2]int) string {
+	items := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		if r[0] == r[1] {
+			items = append(items, strconv.Itoa(r[0]))
+		} else {
+			items = append(items, strconv.Itoa(r[0])+"-"+strconv.Itoa(r[1]))
+		}
+	}
+	return strings.Join(items, ",")
+}
+
+// copyPort 复制端口，避免合并结果与原结果共用slice
+func copyPort(port Port) Port {
+	port.Script = append([]Script(nil), port.Script...)
+	port.Service.CPE = append([]string(nil), port.Service.CPE...)
+	return port
+}
+
+// mergeScripts 按脚本id合并，newer中的脚本覆盖older中的同名脚本
+func mergeScripts(older, newer []Script) []Script {
+	scripts := append([]Script(nil), older...)
+	for _, script := range newer {
+		replaced := false
+		for i := range scripts {
+			if scripts[i].Id == script.Id {
+				scripts[i], replaced = script, true
+				break
+			}
+		}
+		if !replaced {
+			scripts = append(scripts, script)
+		}
+	}
+	return scripts
+}
+
+// mergeOS 按名称合并操作系统识别结果，保留较高的准确率，按准确率从高到低排序
+func mergeOS(older, newer []OS) OS {
+	var merged OS
+	for _, os := range append(append([]OS(nil), older...), newer...) {
+		for _, portUsed := range os.PortUsed {
+			if !containsPortUsed(merged.PortUsed, portUsed) {
+				merged.PortUsed = append(merged.PortUsed, portUsed)
+			}
+		}
+		for _, osMatch := range os.OSMatch {
+			replaced := false
+			for i := range merged.OSMatch {
+				if merged.OSMatch[i].Name == osMatch.Name {
+					if osMatch.Accuracy >= merged.OSMatch[i].Accuracy {
+						merged.OSMatch[i] = osMatch
+					}
+					replaced = true
+					break
+				}
+			}
+			if !replaced {
+				merged.OSMatch = append(merged.OSMatch, osMatch)
+			}
+		}
+		for _, fingerprint := range os.OSFingerPrint {
+			if !containsFingerprint(merged.OSFingerPrint, fingerprint) {
+				merged.OSFingerPrint = append(merged.OSFingerPrint, fingerprint)
+			}
+		}
+	}
+	sort.SliceStable(merged.OSMatch, func(i, j int) bool {
+		return merged.OSMatch[i].Accuracy > merged.OSMatch[j].Accuracy
+	})
+	return merged
+}
+
+func containsFingerprint(fingerprints []OSFingerPrint, fingerprint OSFingerPrint) bool {
+	for _, f := range fingerprints {
+		if f == fingerprint {
+			return true
+		}
+	}
+	return false
+}
+
+func containsPortUsed(portUsed []PortUsed, p PortUsed) bool {
+	for _, u := range portUsed {
+		if u == p {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeTraces 按协议和端口合并traceroute，newer覆盖older
+func mergeTraces(older, newer []Trace) []Trace {
+	traces := append([]Trace(nil), older...)
+	for _, trace := range newer {
+		replaced := false
+		for i := range traces {
+			if traces[i].Proto == trace.Proto && traces[i].Port == trace.Port {
+				traces[i], replaced = trace, true
+				break
+			}
+		}
+		if !replaced {
+			traces = append(traces, trace)
+		}
+	}
+	return traces
+}
@@ -0,0 +1,109 @@
+package nmap
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	scanme := loadXML(t, "testdata/scanme.xml")
+	discovery := loadXML(t, "testdata/discovery.xml")
+	//较新的扫描：22关闭，80的置信度较低，新增udp 53
+	rescan := &NmapXMLResult{
+		Args:  "nmap -sU -sV scanme.nmap.org",
+		Start: scanme.Start + 3600,
+		Host: []Host{{
+			Status:  Status{State: HostStateUp},
+			Address: []Address{{Addr: "45.33.32.156", AddrType: "ipv4"}},
+			Ports: []Ports{{Port: []Port{
+				{Protocol: PortProtocolTcp, PortId: 22, State: State{State: PortState(Closed)}, Service: Service{Name: "ssh", Conf: 3}},
+				{Protocol: PortProtocolTcp, PortId: 80, State: State{State: PortState(Open)}, Service: Service{Name: "http", Conf: 3},
+					Script: []Script{{Id: "http-title", Output: "new title"}}},
+				{Protocol: PortProtocolUdpProto, PortId: 53, State: State{State: PortState(Open)}, Service: Service{Name: "domain", Conf: 10}},
+			}}},
+		}},
+		RunStats: RunStats{Finished: Finished{Time: scanme.Start + 3700, Exit: "success"}},
+	}
+
+	merged, provenance := Merge(rescan, scanme, discovery)
+	hosts := merged.Hosts()
+	if addrs := hosts.Addrs(); len(addrs) != 5 || addrs[0] != "192.168.1.1" || addrs[4] != "45.33.32.156" {
+		t.Fatalf("unexpected hosts %v", addrs)
+	}
+	if merged.Start != discovery.Start || merged.RunStats.Finished.Time != scanme.Start+3700 ||
+		merged.RunStats.Hosts != (Hosts{Up: 4, Down: 1, Total: 5}) {
+		t.Errorf("unexpected run %d %+v", merged.Start, merged.RunStats)
+	}
+
+	//192.168.1.10在两次扫描中都存在，端口来自scanme
+	fileserver := hosts.ByIP("192.168.1.10")
+	if len(fileserver.PortList()) != 4 || fileserver.Hostnames[0].Name != "fileserver.corp.example" || len(fileserver.HostScript) != 2 {
+		t.Errorf("unexpected fileserver %+v", fileserver)
+	}
+	if source := provenance[PortKey{"192.168.1.10", PortProtocolTcp, 445}]; source.Run != 1 || source.Args != scanme.Args {
+		t.Errorf("unexpected provenance %+v", source)
+	}
+
+	host := hosts.ByIP("45.33.32.156")
+	if len(host.PortList()) != 6 || host.PortList()[5].Protocol != PortProtocolUdpProto {
+		t.Fatalf("unexpected ports %d", len(host.PortList()))
+	}
+	if port := host.FindPort(PortProtocolTcp, 22); port.State.State != PortState(Closed) || port.Service.Product != "OpenSSH" || len(port.Script) != 2 {
+		t.Errorf("unexpected port 22 %+v", port)
+	}
+	if port := host.FindPort(PortProtocolTcp, 80); port.Service.Conf != 10 || port.FindScript("http-title").Output != "new title" || len(port.Script) != 3 {
+		t.Errorf("unexpected port 80 %+v", port)
+	}
+	//服务来自置信度更高的scanme
+	if source := provenance[PortKey{"45.33.32.156", PortProtocolTcp, 80}]; source.Run != 0 || source.ServiceRun != 1 || source.ServiceArgs != scanme.Args {
+		t.Errorf("unexpected provenance %+v", source)
+	}
+	if source := provenance[PortKey{"45.33.32.156", PortProtocolTcp, 443}]; source.Run != 1 {
+		t.Errorf("unexpected provenance %+v", source)
+	}
+	//原结果不受影响
+	if len(scanme.Host[0].Ports[0].Port[0].Script) != 2 || scanme.Host[0].Ports[0].Port[1].FindScript("http-title").Output == "new title" {
+		t.Errorf("merge modified the input")
+	}
+
+	cfg := NewConfig()
+	cfg.ResultName = filepath.Join(t.TempDir(), "merged")
+	if err := NewNmap(cfg).ExportResult(merged); err != nil {
+		t.Errorf("export merged result: %v", err)
+	}
+}
+
+func TestMergeSameResult(t *testing.T) {
+	scanme := loadXML(t, "testdata/scanme.xml")
+	merged, _ := Merge(scanme, scanme)
+	if len(merged.HostHint) != len(scanme.HostHint) {
+		t.Errorf("expected %d host hints, but got %d", len(scanme.HostHint), len(merged.HostHint))
+	}
+	host := merged.Hosts().ByIP("45.33.32.156")
+	if extraPorts := host.Ports[0].ExtraPorts; len(extraPorts) != 1 || extraPorts[0].Count != 995 {
+		t.Errorf("unexpected extraports %+v", extraPorts)
+	}
+	if len(host.OS) != 1 || len(host.OS[0].OSFingerPrint) != 1 {
+		t.Errorf("expected 1 fingerprint, but got %+v", host.OS)
+	}
+
+	//较新的扫描列出了未显示的端口1
+	rescan := &NmapXMLResult{
+		Start: scanme.Start + 3600,
+		Host: []Host{{
+			Status:  Status{State: HostStateUp},
+			Address: []Address{{Addr: "45.33.32.156", AddrType: "ipv4"}},
+			Ports:   []Ports{{Port: []Port{{Protocol: PortProtocolTcp, PortId: 1, State: State{State: PortState(Open)}}}}},
+		}},
+	}
+	merged, _ = Merge(rescan, scanme)
+	extraPorts := merged.Hosts().ByIP("45.33.32.156").Ports[0].ExtraPorts
+	if len(extraPorts) != 1 || extraPorts[0].Count != 994 || extraPorts[0].ExtraReasons[0].Count != 994 ||
+		!strings.HasPrefix(extraPorts[0].ExtraReasons[0].Ports, "3-4,6-7,") {
+		t.Errorf("unexpected extraports %+v", extraPorts)
+	}
+	if scanme.Host[0].Ports[0].ExtraPorts[0].Count != 995 {
+		t.Errorf("merge modified the input")
+	}
+}
@@ -1,107 +1,221 @@
 package nmap
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/xml"
 	"fmt"
 	"github.com/pkg/errors"
 	"github.com/xuri/excelize/v2"
-	"io/ioutil"
-	"log"
+	"io"
 	"os"
 	"os/exec"
 	"strconv"
 	"strings"
+	"time"
 )
 
+// defaultGracePeriod 默认的中断等待时间
+const defaultGracePeriod = 5 * time.Second
+
 type nmap struct {
-	Result       string   `json:"result"`
-	Args         []string `json:"args"`
-	BinPath      string   `json:"binPath"`
-	outputType   string
-	ErrOut       error  `json:"errOut"`
-	WarnOut      string `json:"warnOut"`
+	Args    []string `json:"args"`
+	BinPath string   `json:"binPath"`
+	//context取消或超时后，先向nmap发送中断信号，超过GracePeriod仍未退出则强制结束，为0时直接结束
+	GracePeriod time.Duration `json:"gracePeriod"`
+	//导出结果和保存xml原始结果的输出目标，为nil时覆盖写入ResultName对应的文件
+	Sink Sink `json:"-"`
+	//不输出xml，如--script-help
+	noXML        bool
 	exportOption config
+	//进度订阅
+	progressFuncs []ProgressFunc
+	//构建参数时出现的错误，在Validate和Run时返回
+	errs []error
+}
+
+// Result nmap的运行结果
+type Result struct {
+	//标准输出的原始内容，未指定输出到标准输出的格式（如-oN -）时为xml
+	Raw string `json:"raw"`
+	//标准错误输出，作为警告信息
+	Warn string `json:"warn"`
+	//解析后的xml结果，指定了-oN、-oG、-oA等输出时同样可用
+	//扫描被取消或超时时，包含已完成的host
+	XML *NmapXMLResult `json:"xml"`
+	//nmap写入的结果文件，包括-oN、-oX、-oG、-oS和-oA指定的文件
+	Files []OutputFile `json:"files"`
 }
 
 // Run 通过指定context或使用默认context 运行nmap
-func (receiver *nmap) Run(pctx ...context.Context) *nmap {
-	var (
-		stdout, stderr bytes.Buffer
-		err            error
-	)
-	ctx := checkCtx(pctx)
+//
+// context取消或超时时，返回ErrScanCanceled或ErrScanTimeout，同时返回已完成host的结果
+func (receiver *nmap) Run(pctx ...context.Context) (*Result, error) {
+	return receiver.run(nil, pctx)
+}
+
+// RunStream 通过指定context或使用默认context 运行nmap，
+// 使用默认的xml输出时，每个host、hosthint、taskprogress、taskbegin/taskend元素闭合后立即回调handler，最后回调runstats
+func (receiver *nmap) RunStream(handler StreamHandler, pctx ...context.Context) (*Result, error) {
+	return receiver.run(handler, pctx)
+}
+
+func (receiver *nmap) run(handler StreamHandler, pctx []context.Context) (*Result, error) {
+	var stdout, stderr bytes.Buffer
+	if err := receiver.Validate(); err != nil {
+		return nil, err
+	}
+	ctx, err := checkCtx(pctx)
+	if err != nil {
+		return nil, err
+	}
 	err = checkEnvNmap(receiver)
 	if err != nil {
-		return nil
+		return nil, err
 	}
-	//未指定输出，使用默认的-oX -
-	if receiver.outputType == "" {
-		receiver.Args = append(append(receiver.Args, "-oX"), "-")
+	//复制一份参数，多次Run互不影响，同时保留xml通道
+	plan, err := planOutput(receiver.Args, receiver.noXML)
+	if err != nil {
+		return nil, err
 	}
-	cmd := exec.CommandContext(ctx, receiver.BinPath, receiver.Args...)
-
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	done := make(chan error)
-	go func() {
-		defer close(done)
-		err := cmd.Start()
-		if err != nil {
-			log.Fatal(err)
-		}
-		err = cmd.Wait()
+	if plan.temp {
+		defer os.Remove(plan.xmlPath)
+	}
+	//标准输出被其他格式占用，nmap启动前打开xml文件，运行时边读取边解析
+	var xmlFile *os.File
+	if plan.xml && plan.xmlPath != "" {
+		xmlFile, err = plan.openXMLFile()
 		if err != nil {
-			//log.Println("waiting on nmap:", err)
-			done <- err
+			return nil, err
 		}
-	}()
-	select {
-	case <-ctx.Done():
-		_ = cmd.Process.Kill()
-		receiver.ErrOut = errors.New("timeout exceed")
-	case <-done:
-		outStr, errStr := string(stdout.Bytes()), string(stderr.Bytes())
-		//默认的xml输出格式
-		if receiver.outputType == "" {
-			result := &NmapXMLResult{}
-			err = xml.Unmarshal(stdout.Bytes(), &result)
-			// xml解析出错
-			if err != nil {
-				receiver.ErrOut = err
-				return receiver
+		defer xmlFile.Close()
+	}
+	args := plan.args
+	//订阅了进度，需要nmap定期输出taskprogress
+	if len(receiver.progressFuncs) != 0 && !hasArg(args, "--stats-every") {
+		args = append(args, "--stats-every", defaultStatsEvery)
+	}
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var abortErr error
+	if len(receiver.progressFuncs) != 0 {
+		tracker := newProgressTracker(receiver.progressFuncs)
+		streamHandler := handler
+		handler = func(event *StreamEvent) {
+			if streamHandler != nil {
+				streamHandler(event)
+			}
+			if abortErr != nil {
+				return
 			}
-			//cmd stderr 作为提示信息
-			errorMsg := result.RunStats.Finished.ErrorMsg
-			//运行错误信息
-			if len(errorMsg) != 0 {
-				receiver.ErrOut = errors.New(errorMsg)
-				return receiver
+			//进度回调返回error，终止扫描
+			if err := tracker.update(event); err != nil {
+				abortErr = err
+				cancel()
 			}
 		}
-		if len(errStr) != 0 {
-			receiver.WarnOut = errStr
+	}
+	cmd := exec.Command(receiver.BinPath, args...)
+
+	stdoutPipe, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stderr = &stderr
+	err = cmd.Start()
+	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) || errors.Is(err, os.ErrNotExist) {
+			return nil, errors.Wrap(ErrNmapNotFound, err.Error())
 		}
-		if len(outStr) != 0 {
-			receiver.Result = outStr
-			if receiver.exportOption.SaveXmlRaw {
-				var resultName = receiver.exportOption.ResultName
-				if receiver.outputType == "" {
-					resultName = receiver.exportOption.ResultName + ".xml"
-				}
-				content := []byte(receiver.Result)
-				err := ioutil.WriteFile(resultName, content, 0644)
-				if err != nil {
-					receiver.ErrOut = err
-				}
+		return nil, err
+	}
+	exited := make(chan struct{})
+	stopped := make(chan struct{})
+	go stopProcess(runCtx, cmd.Process, receiver.GracePeriod, exited, stopped)
+	//xml在标准输出时边读取边解析，同时保留原始输出
+	var parseErr error
+	xmlResult := &NmapXMLResult{}
+	reader := io.TeeReader(stdoutPipe, &stdout)
+	decoder := newStreamDecoder(reader, xmlResult, handler)
+	var xmlRaw []byte
+	if xmlFile == nil {
+		if plan.xml {
+			parseErr = decoder.decode()
+		}
+		_, _ = io.Copy(io.Discard, reader)
+		xmlRaw = stdout.Bytes()
+	} else {
+		//标准输出关闭时nmap已退出，xml文件不再写入
+		stdoutDone := make(chan struct{})
+		go func() {
+			_, _ = io.Copy(io.Discard, reader)
+			close(stdoutDone)
+		}()
+		var xmlBuf bytes.Buffer
+		xmlReader := io.TeeReader(&followReader{file: xmlFile, done: stdoutDone}, &xmlBuf)
+		decoder = newStreamDecoder(xmlReader, xmlResult, handler)
+		parseErr = decoder.decode()
+		_, _ = io.Copy(io.Discard, xmlReader)
+		<-stdoutDone
+		xmlRaw = xmlBuf.Bytes()
+	}
+	waitErr := cmd.Wait()
+	close(exited)
+	var fileErr error
+	if plan.xml {
+		fileErr = plan.writeXMLFiles(xmlRaw)
+	}
+
+	result := &Result{
+		Raw:   stdout.String(),
+		Warn:  stderr.String(),
+		Files: plan.files,
+	}
+	if isClosed(stopped) {
+		//被终止的扫描xml不完整，返回已完成的host
+		if plan.xml && decoder.root {
+			if !decoder.runStats {
+				synthesizeRunStats(xmlResult, decoder.recovery(xmlRaw, parseErr))
 			}
-			return receiver
+			result.XML = xmlResult
+		}
+		if abortErr != nil {
+			return result, abortErr
 		}
+		return result, ctxErr(ctx.Err())
 	}
-	return receiver
+	var exitErr *exec.ExitError
+	if errors.As(waitErr, &exitErr) {
+		return result, &ErrNmapExit{Code: exitErr.ExitCode(), Stderr: result.Warn}
+	}
+	if waitErr != nil {
+		return result, waitErr
+	}
+	if plan.xml {
+		// xml解析出错
+		if parseErr != nil {
+			return result, &XMLParseError{Err: parseErr}
+		}
+		result.XML = xmlResult
+		//运行错误信息
+		errorMsg := xmlResult.RunStats.Finished.ErrorMsg
+		if len(errorMsg) != 0 {
+			return result, &ErrNmapExit{Stderr: result.Warn, ErrorMsg: errorMsg}
+		}
+		if fileErr != nil {
+			return result, fileErr
+		}
+	}
+	if len(xmlRaw) != 0 && plan.xml && receiver.exportOption.SaveXmlRaw {
+		_, err = receiver.writeSink(receiver.exportOption.ResultName+".xml", func(w io.Writer) error {
+			_, err := w.Write(xmlRaw)
+			return err
+		})
+		if err != nil {
+			return result, err
+		}
+	}
+	return result, nil
 }
 
 // PrettyResult 格式化xml结果到输出
@@ -110,7 +224,7 @@ func (receiver *nmap) PrettyResult(result *NmapXMLResult) {
 	var noHostHint bool
 	if receiver.exportOption.ShowHosthint {
 		for _, hosthint := range result.HostHint {
-			hosthintResult = append(hosthintResult, fmt.Sprintf("\t%s %s ", hosthint.Address.Addr, hosthint.Status.State))
+			hosthintResult = append(hosthintResult, fmt.Sprintf("\t%s %s ", strings.Join(addrList(hosthint.Address), " "), hosthint.Status.State))
 			for _, hostname := range hosthint.Hostnames {
 				hosthintResult = append(hosthintResult, fmt.Sprintf("%s", hostname.Name))
 			}
@@ -139,7 +253,7 @@ func (receiver *nmap) PrettyResult(result *NmapXMLResult) {
 
 	var outResult []any
 	if receiver.exportOption.ShowHostPort || receiver.exportOption.ShowHosthint {
-		for _, host := range result.Host {
+		for _, host := range result.Hosts() {
 			if noHostHint && receiver.exportOption.ShowHosthint {
 				for _, addr := range host.Address {
 					hosthintResult = append(append(hosthintResult, addr.Addr), " ")
@@ -161,22 +275,17 @@ func (receiver *nmap) PrettyResult(result *NmapXMLResult) {
 			if len(host.Ports) != 0 {
 				outResult = append(outResult,
 					fmt.Sprintf(formateHeader, "port", "state", "service", "version", "cpe", "confidence", "reason", "nseresult"))
-				for _, ports := range host.Ports {
-					for _, port := range ports.Port {
-						nseOutput := make([]string, 0)
-						for _, script := range port.Script {
-							//fmt.Print(script.Id, " >>>")
-							//fmt.Println(script.Output)
-							nseOutput = append(append(append(nseOutput, script.Id), script.Output), " &&&& ")
-						}
-						nse := strings.Join(nseOutput, "\n")
-						nse = strings.ReplaceAll(nse, "\n", "")
-						nse = strings.TrimSuffix(nse, " &&&& ")
-						outResult = append(outResult,
-							fmt.Sprintf(formateBody, port.PortId, port.Protocol, port.State.State, port.Service.Name, port.Service.Product,
-								port.Service.Version, port.Service.CPE, port.Service.Conf, port.State.Reason, nse))
+				for _, port := range host.PortList() {
+					nseOutput := make([]string, 0)
+					for _, script := range port.Script {
+						nseOutput = append(append(append(nseOutput, script.Id), script.Output), " &&&& ")
 					}
-
+					nse := strings.Join(nseOutput, "\n")
+					nse = strings.ReplaceAll(nse, "\n", "")
+					nse = strings.TrimSuffix(nse, " &&&& ")
+					outResult = append(outResult,
+						fmt.Sprintf(formateBody, port.PortId, port.Protocol, port.State.State, port.Service.Name, port.Service.Product,
+							port.Service.Version, port.Service.CPE, port.Service.Conf, port.State.Reason, nse))
 				}
 
 				//for _, os := range host.OS {
@@ -203,182 +312,90 @@ func (receiver *nmap) PrettyResult(result *NmapXMLResult) {
 	fmt.Println(result.RunStats.Finished.Summary)
 }
 
-// ParseXmlResult 解析xml结果到NmapXMLResult结构体
-func (receiver *nmap) ParseXmlResult(result any) any {
-	b := []byte(result.(string))
+// ParseXML 解析xml结果到NmapXMLResult结构体
+func ParseXML(data []byte) (*NmapXMLResult, error) {
 	nmapXMLResult := &NmapXMLResult{}
-	err := xml.Unmarshal(b, &nmapXMLResult)
+	err := xml.Unmarshal(data, nmapXMLResult)
 	if err != nil {
-		log.Fatal("Unsupported result format: ", err)
+		return nil, &XMLParseError{Err: err}
 	}
-	return nmapXMLResult
+	return nmapXMLResult, nil
 }
 
-// ExportResult 解析xml结果到Excel文件中
-func (receiver *nmap) ExportResult(result *NmapXMLResult) {
-	target := receiver.exportOption.ResultName + ".xlsx"
-	_, err := os.Stat(target)
-	if err == nil {
-		os.Remove(target)
-	}
+// ExportResult 解析xml结果到Excel文件中，通过Sink写入 ResultName.xlsx
+func (receiver *nmap) ExportResult(result *NmapXMLResult) error {
+	_, err := receiver.ExportFile(context.Background(), "xlsx", result)
+	return err
+}
+
+// writeExcel 按option输出Excel
+func writeExcel(w io.Writer, result *NmapXMLResult, option config) error {
 	file := excelize.NewFile()
 	sheet1Name := "Sheet1"
 	sheet2Name := "Sheet2"
 	_ = file.NewSheet(sheet2Name)
 
-	streamWriter, _ := file.NewStreamWriter(sheet1Name)
-	streamWriter2, _ := file.NewStreamWriter(sheet2Name)
-	setColWidth(streamWriter, 20, 1, 4)
-	setColWidth(streamWriter, 30, 2)
-	setColWidth(streamWriter, 10, 3)
-
-	setColWidth(streamWriter2, 15, 1, 4, 8, 9, 10)
-	setColWidth(streamWriter2, 30, 2, 11, 14)
-	setColWidth(streamWriter2, 10, 3, 12, 13)
-
-	header1 := []string{"address", "hostnames", "state", "reason"}
-	writeHeader(streamWriter, header1)
-	header2 := []string{"address", "hostnames", "_state", "_reason", "port", "protocol", "state", "service", "product", "version", "cpe", "confidence", "reason", "nseresult"}
-	writeHeader(streamWriter2, header2)
-	var noHostHint bool
-	//hosthint
-	for i, hosthint := range result.HostHint {
-		row := make([]any, 4)
-		if len(hosthint.Hostnames) != 0 {
-			host := make([]string, 0)
-			for _, hostname := range hosthint.Hostnames {
-				host = append(host, hostname.Name)
-			}
-			row[1] = strings.Join(host, "\n")
+	streamWriter, err := file.NewStreamWriter(sheet1Name)
+	if err != nil {
+		return err
+	}
+	streamWriter2, err := file.NewStreamWriter(sheet2Name)
+	if err != nil {
+		return err
+	}
+	colWidths := []struct {
+		writer   *excelize.StreamWriter
+		colWidth float64
+		col      []int
+	}{
+		{streamWriter, 20, []int{1, 4}},
+		{streamWriter, 30, []int{2}},
+		{streamWriter, 10, []int{3}},
+		{streamWriter2, 15, []int{1, 4, 8, 9, 10}},
+		{streamWriter2, 30, []int{2, 11, 14}},
+		{streamWriter2, 10, []int{3, 12, 13}},
+	}
+	for _, c := range colWidths {
+		if err := setColWidth(c.writer, c.colWidth, c.col...); err != nil {
+			return err
 		}
-		row[0] = hosthint.Address.Addr
-		row[2] = hosthint.Status.State
-		row[3] = hosthint.Status.Reason
-		index := i + 2
-		writeValue(streamWriter, index, row)
 	}
-	if len(result.HostHint) == 0 {
-		noHostHint = true
+
+	if err := writeHeader(streamWriter, hostHeader); err != nil {
+		return err
+	}
+	if err := writeHeader(streamWriter2, portHeader); err != nil {
+		return err
+	}
+	//hosthint，没有hosthint时为host
+	hosts := hostRows(result)
+	for i, row := range hosts {
+		if err := writeValue(streamWriter, i+2, row); err != nil {
+			return err
+		}
 	}
 
 	//host and ports
 	i := 0
-	mergeSlice := make([]int, 0)
-	for j, host := range result.Host {
-		if noHostHint {
-			row := make([]any, 4)
-			if len(host.Address) != 0 {
-				addrTemp := make([]string, 0)
-				for _, addr := range host.Address {
-					addrTemp = append(addrTemp, addr.Addr)
-				}
-				row[0] = strings.Join(addrTemp, "\n")
-			}
-			if len(host.Hostnames) != 0 {
-				hostTemp := make([]string, 0)
-				for _, hostname := range host.Hostnames {
-					hostTemp = append(hostTemp, hostname.Name)
-				}
-				row[1] = strings.Join(hostTemp, "\n")
+	for _, group := range portRowGroups(result) {
+		start := i + 2
+		for _, row := range group {
+			if err := writeValue(streamWriter2, i+2, row); err != nil {
+				return err
 			}
-			row[2] = host.Status.State
-			row[3] = host.Status.Reason
-			index := j + 2
-			writeValue(streamWriter, index, row)
-		}
-		row := make([]any, 14)
-		if len(host.Address) != 0 {
-			addrTemp := make([]string, 0)
-			for _, addr := range host.Address {
-				addrTemp = append(addrTemp, addr.Addr)
-			}
-			row[0] = strings.Join(addrTemp, "\n")
-		}
-		if len(host.Hostnames) != 0 {
-			hostTemp := make([]string, 0)
-			for _, hostname := range host.Hostnames {
-				hostTemp = append(hostTemp, hostname.Name)
-			}
-			row[1] = strings.Join(hostTemp, "\n")
-		}
-		row[2] = host.Status.State
-		row[3] = host.Status.Reason
-
-		if len(host.Ports) == 0 {
-			writeValue(streamWriter2, i+2, row)
 			i++
-		} else {
-			for _, ports := range host.Ports {
-				for _, port := range ports.Port {
-					//row := make([]any, 13)
-					//if len(host.Address) != 0 {
-					//	addrTemp := make([]string, 0)
-					//	for _, addr := range host.Address {
-					//		addrTemp = append(addrTemp, addr.Addr)
-					//	}
-					//	row[0] = strings.Join(addrTemp, "\n")
-					//}
-					//
-					//if len(host.Hostnames) != 0 {
-					//	hostTemp := make([]string, 0)
-					//	for _, hostname := range host.Hostnames {
-					//		hostTemp = append(hostTemp, hostname.methodName)
-					//	}
-					//	row[1] = strings.Join(hostTemp, "\n")
-					//}
-					//row[2] = host.Status.State
-					//row[3] = host.Status.Reason
-					row[4] = port.PortId
-					row[5] = port.Protocol
-					row[6] = port.State.State
-					row[7] = port.Service.Name
-					row[8] = port.Service.Product
-					row[9] = port.Service.Version
-					if len(port.Service.CPE) != 0 {
-						cpe := make([]string, 0)
-						for _, s := range port.Service.CPE {
-							cpe = append(cpe, s)
-						}
-						row[10] = strings.Join(cpe, "\n")
-					}
-					row[11] = port.Service.Conf
-					row[12] = port.State.Reason
-					nseOutput := make([]string, 0)
-					for _, script := range port.Script {
-						//fmt.Print(script.Id, " >>>")
-						//fmt.Println(script.Output)
-						nseOutput = append(append(append(nseOutput, script.Id), script.Output), strings.Repeat("&", 20))
-					}
-					nse := strings.Join(nseOutput, "\n")
-					nse = strings.TrimSuffix(nse, strings.Repeat("&", 20))
-					row[13] = nse
-
-					index := i + 2
-					writeValue(streamWriter2, index, row)
-					i++
+		}
+		//合并同一host的address hostnames _state _reason
+		if option.MergeRow && len(group) > 1 {
+			for _, col := range []string{"A", "B", "C", "D"} {
+				if err := streamWriter2.MergeCell(col+strconv.Itoa(start), col+strconv.Itoa(i+1)); err != nil {
+					return err
 				}
 			}
-			mergeSlice = append(mergeSlice, i+1)
 		}
-
 	}
 
-	if receiver.exportOption.MergeRow {
-		start := 2
-		end := 0
-		for _, v := range mergeSlice {
-			if v < start {
-				continue
-			}
-			end = v
-			streamWriter2.MergeCell("A"+strconv.Itoa(start), "A"+strconv.Itoa(end))
-			streamWriter2.MergeCell("B"+strconv.Itoa(start), "B"+strconv.Itoa(end))
-			streamWriter2.MergeCell("C"+strconv.Itoa(start), "C"+strconv.Itoa(end))
-			streamWriter2.MergeCell("D"+strconv.Itoa(start), "D"+strconv.Itoa(end))
-			start = v + 1
-		}
-	}
-	if receiver.exportOption.AddTable {
+	if option.AddTable {
 		tableFormat := `{
 		   "table_name": "table",
 		   "table_style": "TableStyleMedium2",
@@ -387,77 +404,88 @@ func (receiver *nmap) ExportResult(result *NmapXMLResult) {
 		   "show_row_stripes": false,
 		   "show_column_stripes": true
 		}`
-		streamWriter.AddTable("A1", "D"+strconv.Itoa(len(result.Host)+1), tableFormat)
-		streamWriter2.AddTable("A1", "N"+strconv.Itoa(i+1), tableFormat)
+		if err := streamWriter.AddTable("A1", "D"+strconv.Itoa(len(hosts)+1), tableFormat); err != nil {
+			return err
+		}
+		if err := streamWriter2.AddTable("A1", "N"+strconv.Itoa(i+1), tableFormat); err != nil {
+			return err
+		}
 	}
 
 	file.SetSheetName(sheet2Name, "host And Ports")
 	file.SetSheetName(sheet1Name, "hosthint")
 
 	if err := streamWriter.Flush(); err != nil {
-		log.Fatal(err)
+		return err
 	}
 	if err := streamWriter2.Flush(); err != nil {
-		log.Fatal(err)
+		return err
 	}
-	if err := file.SaveAs(target); err != nil {
-		log.Fatal(err)
+	if err := exportSheets(file, result, option); err != nil {
+		return err
 	}
+	return file.Write(w)
 }
 
-//ExportTxtResult 导出成txt格式，用于导入魔方资产
-func (receiver *nmap) ExportTxtResult(result *NmapXMLResult) {
-	target := receiver.exportOption.ResultName + ".txt"
-	_, err := os.Stat(target)
-	if err == nil {
-		os.Remove(target)
-	}
-	file, err := os.Create(target)
-	if err != nil {
-		panic(err)
-	}
-	defer file.Close()
-	writer := bufio.NewWriter(file)
-	for _, host := range result.Host {
-		for _, addr := range host.Address {
-			var outTotal []string
-			for _, ports := range host.Ports {
-				for _, port := range ports.Port {
-					var out []any
-					var version = port.Service.Product + port.Service.Version
-					if port.Service.Product == "" {
-						version = "null"
-					}
-					out = append(out, fmt.Sprintf("%d,%s,%s,%s,%s", port.PortId, port.Protocol, port.State.State, port.Service.Name, version))
-					result := fmt.Sprintf("%s,", out)
-					result = strings.TrimRight(result, ",")
-					outTotal = append(outTotal, result)
-				}
-			}
-			var total = addr.Addr
-			if len(outTotal) != 0 {
-				strResult := strings.Join(outTotal, ",")
-				total += "[" + strResult + "]"
-			}
-			fmt.Fprintln(writer, total)
-		}
-		writer.Flush()
-	}
+// ExportTxtResult 导出成txt格式，默认用于导入魔方资产，格式由config的TxtTemplate指定，通过Sink写入 ResultName.txt
+func (receiver *nmap) ExportTxtResult(result *NmapXMLResult) error {
+	_, err := receiver.ExportFile(context.Background(), "txt", result)
+	return err
 }
 
 func NewNmap(cfg ...*config) *nmap {
-	n := &nmap{}
+	n := &nmap{GracePeriod: defaultGracePeriod}
 	// export nmap
-	option := checkOption(cfg)
+	option, err := checkOption(cfg)
+	if err != nil {
+		n.setErr(err)
+		option = NewConfig()
+	}
 	n.exportOption = *option
 	return n
 }
 
+// setErr 记录构建参数时出现的错误，Validate和Run时返回
+func (receiver *nmap) setErr(err error) {
+	receiver.errs = append(receiver.errs, err)
+}
+
+// stopProcess ctx结束后终止nmap，先发送中断信号，nmap可输出已完成的结果，超过grace仍未退出则强制结束
+func stopProcess(ctx context.Context, process *os.Process, grace time.Duration, exited <-chan struct{}, stopped chan<- struct{}) {
+	select {
+	case <-exited:
+		return
+	case <-ctx.Done():
+	}
+	close(stopped)
+	//windows不支持发送中断信号，直接结束
+	if grace <= 0 || process.Signal(os.Interrupt) != nil {
+		_ = process.Kill()
+		return
+	}
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+	select {
+	case <-exited:
+	case <-timer.C:
+		_ = process.Kill()
+	}
+}
+
+func isClosed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
 func checkEnvNmap(receiver *nmap) error {
 	if receiver.BinPath == "" {
 		path, err := exec.LookPath("nmap")
 		if err != nil {
-			return err
+			return errors.Wrap(ErrNmapNotFound, err.Error())
 		} else {
 			receiver.BinPath = path
 		}
@@ -465,66 +493,70 @@ func checkEnvNmap(receiver *nmap) error {
 	return nil
 }
 
-func checkOption(opt []*config) *config {
+func checkOption(opt []*config) (*config, error) {
 	var option *config
 	opLen := len(opt)
 	switch opLen {
 	case 0:
-		option = &config{
-			ResultName:   "Result",
-			ShowHosthint: true,
-			ShowHostPort: true,
-			MergeRow:     true,
-			AddTable:     true,
-			SaveXmlRaw:   true,
-		}
+		option = NewConfig()
 	case 1:
+		if opt[0] == nil {
+			return nil, errors.New("config is nil")
+		}
 		option = opt[0]
 	default:
-		panic("support one nmap only")
+		return nil, errors.New("support one config only")
 	}
-	return option
+	return option, nil
 }
 
-func checkCtx(pctx []context.Context) context.Context {
+func checkCtx(pctx []context.Context) (context.Context, error) {
 	var ctx context.Context
 	pctxLen := len(pctx)
 	switch pctxLen {
 	case 0:
-		ctx, _ = context.WithCancel(context.Background())
+		ctx = context.Background()
 	case 1:
 		ctx = pctx[0]
 	default:
-		panic("support one context only")
+		return nil, errors.New("support one context only")
+	}
+	return ctx, nil
+}
+
+// addrList 地址列表
+func addrList(addrs []Address) []string {
+	list := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		list = append(list, addr.Addr)
 	}
-	return ctx
+	return list
 }
 
-func setColWidth(writer *excelize.StreamWriter, colWidth float64, col ...int) {
+func setColWidth(writer *excelize.StreamWriter, colWidth float64, col ...int) error {
 	for _, v := range col {
 		err := writer.SetColWidth(v, v, colWidth)
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
 	}
+	return nil
 }
 
-func writeValue(writer *excelize.StreamWriter, index int, row []any) {
-	cell, _ := excelize.CoordinatesToCellName(1, index)
-	if err := writer.SetRow(cell, row); err != nil {
-		log.Fatal(err)
+func writeValue(writer *excelize.StreamWriter, index int, row []any) error {
+	cell, err := excelize.CoordinatesToCellName(1, index)
+	if err != nil {
+		return err
 	}
+	return writer.SetRow(cell, row)
 }
 
-func writeHeader(writer *excelize.StreamWriter, header1 []string) {
+func writeHeader(writer *excelize.StreamWriter, header1 []string) error {
 	row := make([]any, len(header1))
 	for i, s := range header1 {
 		row[i] = s
 	}
-	cell, _ := excelize.CoordinatesToCellName(1, 1)
-	if err := writer.SetRow(cell, row); err != nil {
-		log.Fatal(err)
-	}
+	return writeValue(writer, 1, row)
 }
 
 // https://svn.nmap.org/nmap/docs/nmap.usage.txt
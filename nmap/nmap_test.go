@@ -1,20 +1,27 @@
 package nmap
 
 import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"github.com/pkg/errors"
 	"os"
+	"path/filepath"
 	"reflect"
+	"runtime"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestNewNmap(t *testing.T) {
 	path := os.Getenv("PATH")
 	_ = os.Setenv("PATH", "")
 	n := NewNmap()
-	result := n.Run()
-	if result != nil {
-		t.Errorf("Expected nmap not installed, but got nmap is not null")
+	result, err := n.Run()
+	if result != nil || !errors.Is(err, ErrNmapNotFound) {
+		t.Errorf("Expected nmap not installed, but got %v", err)
 	}
 
 	_ = os.Setenv("PATH", path)
@@ -43,14 +50,14 @@ func TestAddArgs(t *testing.T) {
 		{"AddsL", NewNmap(), nil, []string{"-sL"}},
 		{"Addsn", NewNmap(), nil, []string{"-sn"}},
 		{"AddPn", NewNmap(), nil, []string{"-Pn"}},
-		{"AddPS", NewNmap(), []string{"80,8081"}, []string{"-PS", "80,8081"}},
-		{"AddPA", NewNmap(), []string{"80"}, []string{"-PA", "80"}},
-		{"AddPU", NewNmap(), []string{"80"}, []string{"-PU", "80"}},
-		{"AddPY", NewNmap(), []string{"80"}, []string{"-PY", "80"}},
+		{"AddPS", NewNmap(), []string{"80,8081"}, []string{"-PS80,8081"}},
+		{"AddPA", NewNmap(), []string{"80"}, []string{"-PA80"}},
+		{"AddPU", NewNmap(), []string{"80"}, []string{"-PU80"}},
+		{"AddPY", NewNmap(), []string{"80"}, []string{"-PY80"}},
 		{"AddPE", NewNmap(), nil, []string{"-PE"}},
 		{"AddPP", NewNmap(), nil, []string{"-PP"}},
 		{"AddPM", NewNmap(), nil, []string{"-PM"}},
-		{"AddPO", NewNmap(), []string{"80"}, []string{"-PO", "80"}},
+		{"AddPO", NewNmap(), []string{"80"}, []string{"-PO80"}},
 		{"Adddisablearpping", NewNmap(), nil, []string{"--disable-arp-ping"}},
 		{"Addtraceroute", NewNmap(), nil, []string{"--traceroute"}},
 		{"Addn", NewNmap(), nil, []string{"-n"}},
@@ -247,3 +254,172 @@ func TestAddArgs(t *testing.T) {
 		})
 	}
 }
+
+func TestDecodeXMLStream(t *testing.T) {
+	content, err := os.ReadFile("../examples/parsewithfile/nmap_example.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &NmapXMLResult{}
+	if err := xml.Unmarshal(content, expected); err != nil {
+		t.Fatal(err)
+	}
+
+	counts := make(map[StreamEventType]int)
+	var last StreamEventType
+	result := &NmapXMLResult{}
+	err = decodeXMLStream(bytes.NewReader(content), result, func(event *StreamEvent) {
+		counts[event.Type]++
+		last = event.Type
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts[EventHost] != len(expected.Host) || counts[EventTaskBegin] != len(expected.TaskBegin) ||
+		counts[EventTaskProgress] != len(expected.TaskProgress) || counts[EventTaskEnd] != len(expected.TaskEnd) {
+		t.Errorf("unexpected event counts %v", counts)
+	}
+	if last != EventRunStats {
+		t.Errorf("expected runstats as last event, but got %s", last)
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("stream result differs from xml.Unmarshal result")
+	}
+}
+
+func TestProgressTracker(t *testing.T) {
+	var got []Progress
+	tracker := newProgressTracker([]ProgressFunc{func(progress Progress) error {
+		got = append(got, progress)
+		return nil
+	}, ETABudget(time.Hour)})
+	now := time.Now().Unix()
+	events := []*StreamEvent{
+		{Type: EventTaskBegin, TaskBegin: &TaskBegin{Task: "SYN Stealth Scan", Time: now}},
+		{Type: EventTaskProgress, TaskProgress: &TaskProgress{Task: "SYN Stealth Scan", Time: now, Percent: 42.5, Remaining: 60, Etc: now + 60}},
+		{Type: EventHost, Host: &Host{Status: Status{State: HostStateUp}}},
+		{Type: EventRunStats, RunStats: &RunStats{Hosts: Hosts{Up: 1, Down: 1, Total: 2}}},
+	}
+	for _, event := range events {
+		if err := tracker.update(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(got) != len(events) {
+		t.Fatalf("expected %d progress updates, but got %d", len(events), len(got))
+	}
+	if got[1].Percent != 42.5 || got[1].Remaining != time.Minute || got[1].ETA.Unix() != now+60 {
+		t.Errorf("unexpected progress %+v", got[1])
+	}
+	if last := got[3]; !last.Done || last.HostsCompleted != 1 || last.HostsTotal != 2 {
+		t.Errorf("unexpected final progress %+v", last)
+	}
+
+	err := tracker.update(&StreamEvent{Type: EventTaskProgress, TaskProgress: &TaskProgress{Task: "SYN Stealth Scan", Etc: now + 7200}})
+	if err == nil {
+		t.Errorf("expected eta budget exceeded")
+	}
+
+	//已指定的--stats-every不被覆盖
+	for _, args := range [][]string{{"--stats-every", "10s"}, {"--stats-every=10s"}, {"-sS", "--stats-every=1m", "10.0.0.1"}} {
+		if !hasArg(args, "--stats-every") {
+			t.Errorf("expected --stats-every in %v", args)
+		}
+	}
+}
+
+func TestBuildErr(t *testing.T) {
+	cases := []struct {
+		name string
+		nmap *nmap
+	}{
+		{"config", NewNmap(NewConfig(), NewConfig())},
+		{"fileName", NewNmap().AddoN("a", "b")},
+		{"topports", NewNmap().Addtopports(0)},
+		{"portratio", NewNmap().Addportratio(2)},
+		{"verbose", NewNmap().Addv(10)},
+		{"versionintensity", NewNmap().Addversionintensity(42)},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if _, err := c.nmap.Run(); err == nil || errors.Is(err, ErrNmapNotFound) {
+				t.Errorf("expected build error, but got %v", err)
+			}
+		})
+	}
+
+	_, err := ParseXML([]byte("<nmaprun><host>"))
+	if !errors.Is(err, ErrXMLParse) {
+		t.Errorf("expected ErrXMLParse, but got %v", err)
+	}
+}
+
+func TestRunPartialResult(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake nmap is a shell script")
+	}
+	example, err := filepath.Abs("../examples/parsewithfile/nmap_example.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	//输出前两个host后挂起，模拟长时间运行的扫描
+	script := "#!/bin/sh\nawk '{print} /<\\/host>/{n++; if (n==2) exit}' " + example + "\nexec sleep 10\n"
+	binPath := filepath.Join(t.TempDir(), "nmap")
+	if err := os.WriteFile(binPath, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	cfg := NewConfig()
+	cfg.SaveXmlRaw = false
+	n := NewNmap(cfg)
+	n.BinPath = binPath
+	n.GracePeriod = time.Second
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	result, err := n.Run(ctx)
+	if !errors.Is(err, ErrScanTimeout) {
+		t.Fatalf("expected ErrScanTimeout, but got %v", err)
+	}
+	if result == nil || result.XML == nil || len(result.XML.Host) != 2 || !result.XML.RunStats.Incomplete {
+		t.Fatalf("expected 2 finished hosts in partial result")
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(500*time.Millisecond, cancel)
+	if _, err = n.Run(ctx); !errors.Is(err, ErrScanCanceled) {
+		t.Errorf("expected ErrScanCanceled, but got %v", err)
+	}
+}
+
+func TestRecoverXML(t *testing.T) {
+	content, err := os.ReadFile("../examples/parsewithfile/nmap_example.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, recovery, err := RecoverXML(content)
+	if err != nil || !recovery.Complete || result.RunStats.Incomplete {
+		t.Fatalf("expected complete xml, but got %v %v", recovery, err)
+	}
+
+	for _, cut := range []int{len(content) / 3, len(content) / 2, len(content) - 20} {
+		truncated := content[:cut]
+		result, recovery, err = RecoverXML(truncated)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hosts := bytes.Count(truncated, []byte("</host>"))
+		if recovery.Complete || len(result.Host) != hosts {
+			t.Errorf("cut at %d: expected %d hosts, but got %d (%s)", cut, hosts, len(result.Host), recovery)
+		}
+		if !result.RunStats.Incomplete || result.RunStats.Hosts.Total != hosts {
+			t.Errorf("cut at %d: unexpected runstats %+v", cut, result.RunStats)
+		}
+		if recovery.Line < 1 || recovery.Offset > int64(cut) {
+			t.Errorf("cut at %d: unexpected position %s", cut, recovery)
+		}
+	}
+
+	if _, _, err = RecoverXML([]byte("<?xml version=\"1.0\"?>")); !errors.Is(err, ErrXMLParse) {
+		t.Errorf("expected ErrXMLParse, but got %v", err)
+	}
+}
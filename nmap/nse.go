@@ -0,0 +1,319 @@
+package nmap
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// 常用NSE脚本结构化输出的解析
+
+// SSLCert ssl-cert
+type SSLCert struct {
+	Subject    map[string]string  `json:"subject"`
+	Issuer     map[string]string  `json:"issuer"`
+	PubKey     SSLPubKey          `json:"pubkey"`
+	Extensions []SSLCertExtension `json:"extensions,omitempty"`
+	SigAlgo    string             `json:"sigalgo"`
+	//证书有效期，无法解析时为零值
+	NotBefore time.Time `json:"notbefore"`
+	NotAfter  time.Time `json:"notafter"`
+	MD5       string    `json:"md5"`
+	SHA1      string    `json:"sha1"`
+	PEM       string    `json:"pem"`
+}
+
+type SSLPubKey struct {
+	Type     string `json:"type"`
+	Bits     int    `json:"bits"`
+	Exponent string `json:"exponent,omitempty"`
+	Modulus  string `json:"modulus,omitempty"`
+}
+
+type SSLCertExtension struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Critical bool   `json:"critical,omitempty"`
+}
+
+// SSLEnumCiphers ssl-enum-ciphers
+type SSLEnumCiphers struct {
+	//按输出顺序，如TLSv1.2、TLSv1.3
+	Protocols     []TLSProtocol `json:"protocols"`
+	LeastStrength string        `json:"leaststrength"`
+}
+
+type TLSProtocol struct {
+	Version          string      `json:"version"`
+	Ciphers          []TLSCipher `json:"ciphers"`
+	Compressors      []string    `json:"compressors,omitempty"`
+	CipherPreference string      `json:"cipherpreference,omitempty"`
+	Warnings         []string    `json:"warnings,omitempty"`
+}
+
+type TLSCipher struct {
+	Name     string `json:"name"`
+	KexInfo  string `json:"kexinfo"`
+	Strength string `json:"strength"`
+}
+
+// HTTPTitle http-title
+type HTTPTitle struct {
+	Title       string `json:"title"`
+	RedirectURL string `json:"redirecturl,omitempty"`
+}
+
+// HTTPHeaders http-headers，按输出顺序保留重复的header
+type HTTPHeaders struct {
+	Headers     []HTTPHeader `json:"headers"`
+	RequestType string       `json:"requesttype,omitempty"`
+}
+
+type HTTPHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Get 获取第一个同名header的值，忽略大小写
+func (h *HTTPHeaders) Get(name string) string {
+	for _, header := range h.Headers {
+		if strings.EqualFold(header.Name, name) {
+			return header.Value
+		}
+	}
+	return ""
+}
+
+// SSHHostKey ssh-hostkey中的一个key
+type SSHHostKey struct {
+	Type        string `json:"type"`
+	Bits        int    `json:"bits"`
+	Fingerprint string `json:"fingerprint"`
+	Key         string `json:"key"`
+}
+
+// SMBOSDiscovery smb-os-discovery
+type SMBOSDiscovery struct {
+	OS         string `json:"os"`
+	LanManager string `json:"lanmanager"`
+	Server     string `json:"server"`
+	Date       string `json:"date"`
+	FQDN       string `json:"fqdn"`
+	Domain     string `json:"domain"`
+	Forest     string `json:"forest"`
+	Workgroup  string `json:"workgroup"`
+	CPE        string `json:"cpe"`
+}
+
+// DNSNSID dns-nsid
+type DNSNSID struct {
+	NSID        string `json:"nsid"`
+	IDServer    string `json:"idserver"`
+	BindVersion string `json:"bindversion"`
+}
+
+// ParseSSLCert 解析ssl-cert脚本
+func ParseSSLCert(script Script) (*SSLCert, error) {
+	if err := checkScript(script, "ssl-cert", true); err != nil {
+		return nil, err
+	}
+	t := script.table()
+	pubKey := t.table("pubkey")
+	cert := &SSLCert{
+		Subject: elemMap(t.table("subject")),
+		Issuer:  elemMap(t.table("issuer")),
+		PubKey: SSLPubKey{
+			Type:     pubKey.elem("type"),
+			Bits:     atoi(pubKey.elem("bits")),
+			Exponent: pubKey.elem("exponent"),
+			Modulus:  pubKey.elem("modulus"),
+		},
+		SigAlgo:   t.elem("sig_algo"),
+		NotBefore: parseCertTime(t.table("validity").elem("notBefore")),
+		NotAfter:  parseCertTime(t.table("validity").elem("notAfter")),
+		MD5:       t.elem("md5"),
+		SHA1:      t.elem("sha1"),
+		PEM:       t.elem("pem"),
+	}
+	for _, ext := range t.table("extensions").Table {
+		cert.Extensions = append(cert.Extensions, SSLCertExtension{
+			Name:     ext.elem("name"),
+			Value:    ext.elem("value"),
+			Critical: ext.elem("critical") == "true",
+		})
+	}
+	return cert, nil
+}
+
+// ParseSSLEnumCiphers 解析ssl-enum-ciphers脚本
+func ParseSSLEnumCiphers(script Script) (*SSLEnumCiphers, error) {
+	if err := checkScript(script, "ssl-enum-ciphers", true); err != nil {
+		return nil, err
+	}
+	t := script.table()
+	result := &SSLEnumCiphers{LeastStrength: t.elem("least strength")}
+	for _, table := range t.Table {
+		protocol := TLSProtocol{
+			Version:          table.Key,
+			Compressors:      elemList(table.table("compressors")),
+			CipherPreference: table.elem("cipher preference"),
+			Warnings:         elemList(table.table("warnings")),
+		}
+		for _, cipher := range table.table("ciphers").Table {
+			protocol.Ciphers = append(protocol.Ciphers, TLSCipher{
+				Name:     cipher.elem("name"),
+				KexInfo:  cipher.elem("kex_info"),
+				Strength: cipher.elem("strength"),
+			})
+		}
+		result.Protocols = append(result.Protocols, protocol)
+	}
+	return result, nil
+}
+
+// ParseHTTPTitle 解析http-title脚本，没有结构化输出时使用output
+func ParseHTTPTitle(script Script) (*HTTPTitle, error) {
+	if err := checkScript(script, "http-title", false); err != nil {
+		return nil, err
+	}
+	t := script.table()
+	title := &HTTPTitle{
+		Title:       t.elem("title"),
+		RedirectURL: t.elem("redirect_url"),
+	}
+	if len(t.Elem) == 0 {
+		title.Title = strings.TrimSpace(script.Output)
+	}
+	return title, nil
+}
+
+// ParseHTTPHeaders 解析http-headers脚本，该脚本的结构化输出为每行一个elem，旧版本只有output
+func ParseHTTPHeaders(script Script) (*HTTPHeaders, error) {
+	if err := checkScript(script, "http-headers", false); err != nil {
+		return nil, err
+	}
+	lines := elemList(script.table())
+	if len(lines) == 0 {
+		lines = strings.Split(script.Output, "\n")
+	}
+	headers := &HTTPHeaders{}
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		//(Request type: HEAD)
+		if strings.HasPrefix(line, "(Request type:") {
+			headers.RequestType = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(line, "(Request type:"), ")"))
+			continue
+		}
+		name, value, _ := strings.Cut(line, ":")
+		headers.Headers = append(headers.Headers, HTTPHeader{Name: strings.TrimSpace(name), Value: strings.TrimSpace(value)})
+	}
+	return headers, nil
+}
+
+// ParseSSHHostKey 解析ssh-hostkey脚本
+func ParseSSHHostKey(script Script) ([]SSHHostKey, error) {
+	if err := checkScript(script, "ssh-hostkey", true); err != nil {
+		return nil, err
+	}
+	var keys []SSHHostKey
+	for _, table := range script.Table {
+		keys = append(keys, SSHHostKey{
+			Type:        table.elem("type"),
+			Bits:        atoi(table.elem("bits")),
+			Fingerprint: table.elem("fingerprint"),
+			Key:         table.elem("key"),
+		})
+	}
+	return keys, nil
+}
+
+// ParseSMBOSDiscovery 解析smb-os-discovery脚本，去掉NetBIOS名称末尾的\x00
+func ParseSMBOSDiscovery(script Script) (*SMBOSDiscovery, error) {
+	if err := checkScript(script, "smb-os-discovery", true); err != nil {
+		return nil, err
+	}
+	t := script.table()
+	return &SMBOSDiscovery{
+		OS:         t.elem("os"),
+		LanManager: t.elem("lanmanager"),
+		Server:     strings.TrimSuffix(t.elem("server"), `\x00`),
+		Date:       t.elem("date"),
+		FQDN:       t.elem("fqdn"),
+		Domain:     t.elem("domain_dns"),
+		Forest:     t.elem("forest_dns"),
+		Workgroup:  strings.TrimSuffix(t.elem("workgroup"), `\x00`),
+		CPE:        t.elem("cpe"),
+	}, nil
+}
+
+// ParseDNSNSID 解析dns-nsid脚本
+func ParseDNSNSID(script Script) (*DNSNSID, error) {
+	if err := checkScript(script, "dns-nsid", true); err != nil {
+		return nil, err
+	}
+	t := script.table()
+	return &DNSNSID{
+		NSID:        t.elem("NSID"),
+		IDServer:    t.elem("id.server"),
+		BindVersion: t.elem("bind.version"),
+	}, nil
+}
+
+// ParseBanner 解析banner脚本
+func ParseBanner(script Script) (string, error) {
+	if err := checkScript(script, "banner", false); err != nil {
+		return "", err
+	}
+	return script.Output, nil
+}
+
+// checkScript 检查脚本id，structured为true时要求有结构化输出
+func checkScript(script Script, id string, structured bool) error {
+	if script.Id != id {
+		return errors.Errorf("script %s is not %s", script.Id, id)
+	}
+	if structured && len(script.Table)+len(script.Elem) == 0 {
+		return errors.Errorf("script %s has no structured output", id)
+	}
+	return nil
+}
+
+// elemMap table中有key的elem
+func elemMap(t Table) map[string]string {
+	value := make(map[string]string, len(t.Elem))
+	for _, elem := range t.Elem {
+		if elem.Key != "" {
+			value[elem.Key] = elem.Text
+		}
+	}
+	return value
+}
+
+// elemList table中所有elem的值
+func elemList(t Table) []string {
+	var value []string
+	for _, elem := range t.Elem {
+		value = append(value, elem.Text)
+	}
+	return value
+}
+
+func atoi(s string) int {
+	i, _ := strconv.Atoi(s)
+	return i
+}
+
+// parseCertTime ssl-cert的时间格式，如 2022-03-01T00:00:00，部分版本带时区
+func parseCertTime(s string) time.Time {
+	for _, layout := range []string{"2006-01-02T15:04:05", time.RFC3339} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
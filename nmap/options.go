@@ -0,0 +1,305 @@
+package nmap
+
+import (
+	"regexp"
+	"strings"
+)
+
+// OptionCategory 参数的分类，与man page的分类（本包的文件）对应
+type OptionCategory string
+
+const (
+	//Target Specification，如-iL、--exclude
+	CategoryTarget OptionCategory = "target"
+	//Host Discovery，如-sn、-Pn、-PS
+	CategoryDiscovery OptionCategory = "discovery"
+	//Scan Techniques，如-sS、-sU
+	CategoryTechnique OptionCategory = "technique"
+	//Port Specification and Scan Order，如-p、--top-ports
+	CategoryPort OptionCategory = "port"
+	//Service/Version Detection，如-sV
+	CategoryService OptionCategory = "service"
+	//Script Scan，如-sC、--script
+	CategoryScript OptionCategory = "script"
+	//OS Detection，如-O
+	CategoryOS OptionCategory = "os"
+	//Timing and Performance，如-T、--min-rate
+	CategoryTiming OptionCategory = "timing"
+	//Firewall/IDS Evasion and Spoofing，如-f、-D
+	CategoryEvasion OptionCategory = "evasion"
+	//Output，如-oX、-v、--reason
+	CategoryOutput OptionCategory = "output"
+	//Misc，如-6、-A，以及未知的参数
+	CategoryMisc OptionCategory = "misc"
+)
+
+// optionSpec 参数的含义
+type optionSpec struct {
+	category OptionCategory
+	//是否带值，如-p 80、-T4
+	value bool
+	//值可以省略且只能紧跟参数，如-PS、-PS22,80，-PS 80中的80为目标
+	optional bool
+	//需要root权限，没有时nmap退出（如-sS、-O），
+	//没有root权限时nmap改用其他方式或忽略的参数（如-PE、-f）不标记
+	root bool
+	//不支持IPv6
+	noIPv6 bool
+	//需要同时指定其中一个参数
+	requires []string
+	//检查值，返回问题描述
+	check func(value string) string
+}
+
+// optionTable 按分类的参数含义，本包中Add*方法生成的参数均在其中
+var optionTable = map[OptionCategory]map[string]optionSpec{
+	CategoryTarget: {
+		"-iL":           {value: true},
+		"-iR":           {value: true, noIPv6: true, check: intRange(0, -1)},
+		"--exclude":     {value: true},
+		"--excludefile": {value: true},
+		"--resolve-all": {},
+		"--unique":      {},
+	},
+	CategoryDiscovery: {
+		"-sL":                    {},
+		"-sn":                    {},
+		"-Pn":                    {},
+		"-PS":                    {value: true, optional: true},
+		"-PA":                    {value: true, optional: true},
+		"-PU":                    {value: true, optional: true},
+		"-PY":                    {value: true, optional: true},
+		"-PE":                    {},
+		"-PP":                    {noIPv6: true},
+		"-PM":                    {noIPv6: true},
+		"-PO":                    {value: true, optional: true},
+		"-PR":                    {},
+		"--disable-arp-ping":     {},
+		"--discovery-ignore-rst": {},
+		"--traceroute":           {root: true},
+		"-n":                     {},
+		"-R":                     {},
+		"--dns-servers":          {value: true},
+		"--system-dns":           {},
+	},
+	CategoryTechnique: {
+		"-sS":         {root: true},
+		"-sT":         {},
+		"-sA":         {root: true},
+		"-sW":         {root: true},
+		"-sM":         {root: true},
+		"-sU":         {root: true},
+		"-sN":         {root: true},
+		"-sF":         {root: true},
+		"-sX":         {root: true},
+		"--scanflags": {value: true},
+		"-sI":         {value: true, root: true},
+		"-sY":         {root: true},
+		"-sZ":         {root: true},
+		"-sO":         {root: true},
+		"-b":          {value: true, noIPv6: true},
+	},
+	CategoryPort: {
+		"-p":              {value: true},
+		"--exclude-ports": {value: true},
+		"-F":              {},
+		"-r":              {},
+		"--top-ports":     {value: true, check: intRange(1, -1)},
+		"--port-ratio":    {value: true, check: ratio},
+	},
+	CategoryService: {
+		"-sV":                 {},
+		"--allports":          {requires: []string{"-sV", "-A"}},
+		"--version-intensity": {value: true, requires: []string{"-sV", "-A"}, check: intRange(0, 9)},
+		"--version-light":     {requires: []string{"-sV", "-A"}},
+		"--version-all":       {requires: []string{"-sV", "-A"}},
+		"--version-trace":     {requires: []string{"-sV", "-A"}},
+	},
+	CategoryScript: {
+		"-sC":                {},
+		"--script":           {value: true},
+		"--script-args":      {value: true, requires: scriptOptions},
+		"--script-args-file": {value: true, requires: scriptOptions},
+		"--script-trace":     {requires: scriptOptions},
+		"--script-timeout":   {value: true, requires: scriptOptions, check: timeValue},
+		"--script-updatedb":  {},
+		"--script-help":      {value: true},
+	},
+	CategoryOS: {
+		"-O":             {root: true},
+		"--osscan-limit": {requires: []string{"-O", "-A"}},
+		"--osscan-guess": {requires: []string{"-O", "-A"}},
+		"--max-os-tries": {value: true, requires: []string{"-O", "-A"}, check: intRange(1, 50)},
+	},
+	CategoryTiming: {
+		"-T":                      {value: true, check: timing},
+		"--min-hostgroup":         {value: true, check: intRange(1, -1)},
+		"--max-hostgroup":         {value: true, check: intRange(1, -1)},
+		"--min-parallelism":       {value: true, check: intRange(1, -1)},
+		"--max-parallelism":       {value: true, check: intRange(1, -1)},
+		"--min-rtt-timeout":       {value: true, check: timeValue},
+		"--max-rtt-timeout":       {value: true, check: timeValue},
+		"--initial-rtt-timeout":   {value: true, check: timeValue},
+		"--max-retries":           {value: true, check: intRange(0, -1)},
+		"--host-timeout":          {value: true, check: timeValue},
+		"--scan-delay":            {value: true, check: timeValue},
+		"--max-scan-delay":        {value: true, check: timeValue},
+		"--min-rate":              {value: true, check: positive},
+		"--max-rate":              {value: true, check: positive},
+		"--defeat-rst-ratelimit":  {},
+		"--defeat-icmp-ratelimit": {},
+		"--nsock-engine":          {value: true},
+	},
+	CategoryEvasion: {
+		"-f":                {},
+		"--mtu":             {value: true, check: mtu},
+		"-D":                {value: true},
+		"-S":                {value: true},
+		"-e":                {value: true},
+		"-g":                {value: true, check: intRange(0, 65535)},
+		"--source-port":     {value: true, check: intRange(0, 65535)},
+		"--proxies":         {value: true},
+		"--data":            {value: true},
+		"--data-string":     {value: true},
+		"--data-length":     {value: true, check: intRange(0, 65400)},
+		"--ip-options":      {value: true, noIPv6: true},
+		"--ttl":             {value: true, check: intRange(0, 255)},
+		"--randomize-hosts": {},
+		"--spoof-mac":       {value: true},
+		"--badsum":          {},
+		"--adler32":         {},
+	},
+	CategoryOutput: {
+		"-oN":              {value: true},
+		"-oX":              {value: true},
+		"-oS":              {value: true},
+		"-oG":              {value: true},
+		"-oM":              {value: true},
+		"-oA":              {value: true},
+		"--open":           {},
+		"--packet-trace":   {},
+		"--reason":         {},
+		"--append-output":  {requires: []string{"-oN", "-oX", "-oS", "-oG", "-oM", "-oA"}},
+		"--resume":         {value: true},
+		"--noninteractive": {},
+		"--stylesheet":     {value: true},
+		"--webxml":         {},
+		"--no-stylesheet":  {},
+		"--stats-every":    {value: true, check: timeValue},
+		"--iflist":         {},
+		"--log-errors":     {},
+	},
+	CategoryMisc: {
+		"-6":               {},
+		"-A":               {root: true},
+		"--datadir":        {value: true},
+		"--servicedb":      {value: true},
+		"--versiondb":      {value: true},
+		"--send-eth":       {},
+		"--send-ip":        {},
+		"--privileged":     {},
+		"--unprivileged":   {},
+		"--release-memory": {},
+		"-V":               {},
+		"-h":               {},
+	},
+}
+
+// optionSpecs 所有参数的含义
+var optionSpecs = func() map[string]optionSpec {
+	specs := make(map[string]optionSpec)
+	for category, options := range optionTable {
+		for name, spec := range options {
+			spec.category = category
+			specs[name] = spec
+		}
+	}
+	return specs
+}()
+
+// 需要--script、-sC或-A
+var scriptOptions = []string{"--script", "-sC", "-A"}
+
+var (
+	//带值且值可以紧跟参数的短参数，如-T4、-p80、-PS22,80
+	attachedOptions = []string{"-PS", "-PA", "-PU", "-PY", "-PO", "-T", "-p", "-g", "-e", "-D", "-S"}
+	//-v、-vv、-v3、-d、-dd、-d3
+	levelRegexp = regexp.MustCompile(`^-(v+|d+|v\d|d\d)$`)
+)
+
+// Option 一个参数及其值
+type Option struct {
+	Name string `json:"name"`
+	//没有值的参数（如-sV）为空
+	Value string `json:"value,omitempty"`
+	//值紧跟参数，如-T4、--min-rate=100
+	attached bool
+	//带值的参数缺少值，如最后一个参数为-p
+	missing bool
+}
+
+// Category 参数的分类，未知的参数为CategoryMisc
+func (o Option) Category() OptionCategory {
+	return optionCategory(o.Name)
+}
+
+// args 参数对应的命令行参数
+func (o Option) args() []string {
+	spec, ok := optionSpecs[o.Name]
+	switch {
+	case !ok && o.Value == "" || ok && !spec.value || o.missing:
+		return []string{o.Name}
+	case o.attached && !contains(attachedOptions, o.Name):
+		return []string{o.Name + "=" + o.Value}
+	case o.attached || spec.optional:
+		return []string{o.Name + o.Value}
+	}
+	return []string{o.Name, o.Value}
+}
+
+func optionCategory(name string) OptionCategory {
+	if spec, ok := optionSpecs[name]; ok {
+		return spec.category
+	}
+	if levelRegexp.MatchString(name) {
+		return CategoryOutput
+	}
+	return CategoryMisc
+}
+
+// parseArgs 按optionSpecs解析参数，返回参数和目标
+//
+// 未知的参数视为不带值，其后不以-开头的参数视为目标
+func parseArgs(args []string) ([]Option, []string) {
+	var options []Option
+	var targets []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			targets = append(targets, arg)
+			continue
+		}
+		option := Option{Name: arg}
+		//--min-rate=100，以及单个-的长参数，如-oX=scan.xml
+		if name, value, ok := strings.Cut(arg, "="); ok && (strings.HasPrefix(arg, "--") || optionSpecs[name].value) {
+			option = Option{Name: name, Value: value, attached: true}
+		} else if _, ok := optionSpecs[arg]; !ok && !strings.HasPrefix(arg, "--") {
+			for _, prefix := range attachedOptions {
+				if strings.HasPrefix(arg, prefix) && len(arg) > len(prefix) {
+					option = Option{Name: prefix, Value: arg[len(prefix):], attached: true}
+					break
+				}
+			}
+		}
+		if spec, ok := optionSpecs[option.Name]; ok && spec.value && !spec.optional && !option.attached {
+			if i+1 < len(args) {
+				i++
+				option.Value = args[i]
+			} else {
+				option.missing = true
+			}
+		}
+		options = append(options, option)
+	}
+	return options, targets
+}
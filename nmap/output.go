@@ -1,6 +1,7 @@
 package nmap
 
 import (
+	"github.com/pkg/errors"
 	"strings"
 )
 
@@ -30,9 +31,12 @@ Nmap also offers options to control scan verbosity and to append to output files
 //
 //Requests that normal output be directed to the given filename. As discussed above, this differs slightly from interactive output.
 func (receiver *nmap) AddoN(fileName ...string) *nmap {
-	checkFileNameLen(fileName)
-	receiver.outputType = "oN"
-	return AddArgs(receiver, "-oN", fileName[0])
+	name, err := checkFileNameLen(fileName)
+	if err != nil {
+		receiver.setErr(err)
+		return receiver
+	}
+	return AddArgs(receiver, "-oN", name)
 }
 
 // AddoX -oX <filespec> (XML output)
@@ -43,18 +47,24 @@ func (receiver *nmap) AddoN(fileName ...string) *nmap {
 //
 //The XML output references an XSL stylesheet which can be used to format the results as HTML. The easiest way to use this is simply to load the XML output in a web browser such as Firefox or IE. By default, this will only work on the machine you ran Nmap on (or a similarly configured one) due to the hard-coded nmap.xsl filesystem path. Use the --webxml or --stylesheet options to create portable XML files that render as HTML on any web-connected machine.
 func (receiver *nmap) AddoX(fileName ...string) *nmap {
-	checkFileNameLen(fileName)
-	receiver.outputType = "oX"
-	return AddArgs(receiver, "-oX", fileName[0])
+	name, err := checkFileNameLen(fileName)
+	if err != nil {
+		receiver.setErr(err)
+		return receiver
+	}
+	return AddArgs(receiver, "-oX", name)
 }
 
 //AddoS -oS <filespec> (ScRipT KIdd|3 oUTpuT)
 //
 //Script kiddie output is like interactive output, except that it is post-processed to better suit the l33t HaXXorZ who previously looked down on Nmap due to its consistent capitalization and spelling. Humor impaired people should note that this nmap is making fun of the script kiddies before flaming me for supposedly “helping them”.
 func (receiver *nmap) AddoS(fileName ...string) *nmap {
-	checkFileNameLen(fileName)
-	receiver.outputType = "oS"
-	return AddArgs(receiver, "-oS", fileName[0])
+	name, err := checkFileNameLen(fileName)
+	if err != nil {
+		receiver.setErr(err)
+		return receiver
+	}
+	return AddArgs(receiver, "-oS", name)
 }
 
 //AddoG -oG <filespec> (grepable output)
@@ -68,9 +78,12 @@ func (receiver *nmap) AddoS(fileName ...string) *nmap {
 //
 //As with XML output, this man page does not allow for documenting the entire format. A more detailed look at the Nmap grepable output format is available in the section called “Grepable Output (-oG)”.
 func (receiver *nmap) AddoG(fileName ...string) *nmap {
-	checkFileNameLen(fileName)
-	receiver.outputType = "oG"
-	return AddArgs(receiver, "-oG", fileName[0])
+	name, err := checkFileNameLen(fileName)
+	if err != nil {
+		receiver.setErr(err)
+		return receiver
+	}
+	return AddArgs(receiver, "-oG", name)
 }
 
 //AddoA -oA <basename>: outputType in the three major formats at once
@@ -79,7 +92,6 @@ func (receiver *nmap) AddoG(fileName ...string) *nmap {
 //
 //As a convenience, you may specify -oA <basename> to store scan results in normal, XML, and grepable formats at once. They are stored in <basename>.nmap, <basename>.xml, and <basename>.gnmap, respectively. As with most programs, you can prefix the filenames with a directory path, such as ~/nmaplogs/foocorp/ on Unix or c:\hacking\sco on Windows.
 func (receiver *nmap) AddoA(basename string) *nmap {
-	receiver.outputType = "oA"
 	return AddArgs(receiver, "-oA", basename)
 }
 
@@ -92,7 +104,8 @@ func (receiver *nmap) AddoA(basename string) *nmap {
 //Most changes only affect interactive output, and some also affect normal and script kiddie output. The other output types are meant to be processed by machines, so Nmap can give substantial detail by default in those formats without fatiguing a human user. However, there are a few changes in other modes where output size can be reduced substantially by omitting some detail. For example, a comment line in the grepable output that provides a list of all ports scanned is only printed in verbose mode because it can be quite long.
 func (receiver *nmap) Addv(level int) *nmap {
 	if level < 1 || level > 9 {
-		panic("level scope: [0-9]")
+		receiver.setErr(&ValidationError{Kind: ValidationRange, Option: "-v", Message: "level scope: [1-9]"})
+		return receiver
 	}
 	var s []string
 	for i := 1; i <= level; i++ {
@@ -110,7 +123,8 @@ func (receiver *nmap) Addv(level int) *nmap {
 //Debugging output is useful when a bug is suspected in Nmap, or if you are simply confused as to what Nmap is doing and why. As this feature is mostly intended for developers, debug lines aren't always self-explanatory. You may get something like: Timeout vals: srtt: -1 rttvar: -1 to: 1000000 delta 14987 ==> srtt: 14987 rttvar: 14987 to: 100000. If you don't understand a line, your only recourses are to ignore it, look it up in the source code, or request help from the development list (nmap-dev). Some lines are self explanatory, but the messages become more obscure as the debug level is increased.
 func (receiver *nmap) Addd(level int) *nmap {
 	if level < 1 || level > 9 {
-		panic("level scope: [0-9]")
+		receiver.setErr(&ValidationError{Kind: ValidationRange, Option: "-d", Message: "level scope: [1-9]"})
+		return receiver
 	}
 	var s []string
 	for i := 1; i <= level; i++ {
@@ -217,8 +231,14 @@ func (receiver *nmap) Addnostylesheet() *nmap {
 	return AddArgs(receiver, "--no-stylesheet")
 }
 
-func checkFileNameLen(fileName []string) {
-	if len(fileName) > 1 {
-		panic("fileName should be zero or one")
+// checkFileNameLen 未指定文件名时输出到标准输出
+func checkFileNameLen(fileName []string) (string, error) {
+	switch len(fileName) {
+	case 0:
+		return "-", nil
+	case 1:
+		return fileName[0], nil
+	default:
+		return "", errors.New("fileName should be zero or one")
 	}
 }
@@ -0,0 +1,160 @@
+package nmap
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	//Host: 45.33.32.156 (scanme.nmap.org)
+	grepableHostRegexp = regexp.MustCompile(`^Host: (\S+) \((.*)\)$`)
+	//端口的开始，如 22/open/
+	grepablePortRegexp = regexp.MustCompile(`^\d+/[^/]*/`)
+	//closed (996)
+	grepableIgnoredRegexp = regexp.MustCompile(`^(\S+) \((\d+)\)$`)
+)
+
+// ParseGrepable 解析nmap -oG的结果，包括host状态、端口、服务、忽略的端口数、操作系统和序列号
+//
+// grepable格式不区分product和version，均保存在Service.Product中，括号中的内容保存在Service.ExtraInfo中
+func ParseGrepable(data []byte) (*NmapXMLResult, error) {
+	result := newOutputResult()
+	scanner := newLineScanner(data)
+	lineNum := 0
+	found := false
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.HasPrefix(line, "#") {
+			if parseRunLine(result, line) {
+				found = true
+			}
+			continue
+		}
+		if !strings.HasPrefix(line, "Host: ") {
+			continue
+		}
+		found = true
+		if err := parseGrepableLine(result, line); err != nil {
+			return nil, errors.Wrapf(ErrOutputParse, "grepable line %d: %s", lineNum, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, errors.Wrap(ErrOutputParse, "not nmap grepable output")
+	}
+	finishOutputResult(result)
+	return result, nil
+}
+
+// parseGrepableLine 解析Host行，同一host的Status行和Ports行合并为一个host
+func parseGrepableLine(result *NmapXMLResult, line string) error {
+	fields := strings.Split(line, "\t")
+	m := grepableHostRegexp.FindStringSubmatch(fields[0])
+	if m == nil {
+		return errors.Errorf("invalid host field %q", fields[0])
+	}
+	var host *Host
+	if n := len(result.Host); n != 0 && result.Host[n-1].IP() == m[1] {
+		host = &result.Host[n-1]
+	} else {
+		result.Host = append(result.Host, Host{Address: []Address{hostAddress(m[1])}})
+		host = &result.Host[len(result.Host)-1]
+		if m[2] != "" {
+			host.Hostnames = []Hostname{{Name: m[2]}}
+		}
+	}
+	for _, field := range fields[1:] {
+		name, value, ok := strings.Cut(field, ": ")
+		if !ok {
+			continue
+		}
+		switch name {
+		case "Status":
+			host.Status.State = HostState(strings.ToLower(value))
+		case "Ports", "Protocols":
+			ports, err := parseGrepablePorts(value, name == "Protocols")
+			if err != nil {
+				return err
+			}
+			if len(host.Ports) == 0 {
+				host.Ports = []Ports{{}}
+			}
+			host.Ports[0].Port = append(host.Ports[0].Port, ports...)
+			//有端口的host为up
+			if host.Status.State == "" {
+				host.Status.State = HostStateUp
+			}
+		case "Ignored State":
+			im := grepableIgnoredRegexp.FindStringSubmatch(value)
+			if im == nil {
+				continue
+			}
+			count, _ := strconv.Atoi(im[2])
+			if len(host.Ports) == 0 {
+				host.Ports = []Ports{{}}
+			}
+			host.Ports[0].ExtraPorts = append(host.Ports[0].ExtraPorts, ExtraPorts{State: PortState(im[1]), Count: count})
+		case "OS":
+			host.OS = append(host.OS, OS{OSMatch: []OSMatch{{Name: value}}})
+		case "Seq Index":
+			index, _ := strconv.Atoi(value)
+			host.TCPSequence = append(host.TCPSequence, TCPSequence{Index: index})
+		case "IP ID Seq":
+			host.IpIdSequence = append(host.IpIdSequence, IpIdSequence{Class: value})
+		}
+	}
+	return nil
+}
+
+// parseGrepablePorts 解析端口，如 22/open/tcp//ssh//OpenSSH 6.6.1p1 (protocol 2.0)/, 443/open/tcp//ssl|http//nginx/
+//
+// ip协议扫描（-sO）的格式为 1/open/icmp/
+func parseGrepablePorts(value string, protocols bool) ([]Port, error) {
+	var ports []Port
+	for _, entry := range splitGrepablePorts(value) {
+		parts := strings.Split(entry, "/")
+		if len(parts) < 3 || !protocols && len(parts) < 7 {
+			return nil, errors.Errorf("invalid port %q", entry)
+		}
+		id, err := strconv.ParseUint(parts[0], 10, 16)
+		if err != nil {
+			return nil, errors.Errorf("invalid port %q", entry)
+		}
+		port := Port{PortId: uint16(id), State: State{State: PortState(parts[1])}}
+		if protocols {
+			port.Protocol = PortProtocolIp
+			port.Service.Name = parts[2]
+		} else {
+			port.Protocol = PortProtocol(parts[2])
+			port.Owner.Name = parts[3]
+			name := parts[4]
+			if tunnel, service, ok := strings.Cut(name, "|"); ok {
+				port.Service.Tunnel = tunnel
+				name = service
+			}
+			port.Service.Name = name
+			splitServiceVersion(&port.Service, strings.ReplaceAll(parts[6], "|", "/"))
+		}
+		ports = append(ports, port)
+	}
+	return ports, nil
+}
+
+// splitGrepablePorts 按 ", " 分隔端口，版本中的 ", " 不作为分隔
+func splitGrepablePorts(value string) []string {
+	var entries []string
+	for _, part := range strings.Split(value, ", ") {
+		if n := len(entries); n != 0 && !grepablePortRegexp.MatchString(part) {
+			entries[n-1] += ", " + part
+			continue
+		}
+		entries = append(entries, part)
+	}
+	return entries
+}
@@ -0,0 +1,382 @@
+package nmap
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	//Nmap scan report for scanme.nmap.org (45.33.32.156)，-v时未开放的host为 Nmap scan report for 192.168.1.2 [host down]
+	reportRegexp = regexp.MustCompile(`^Nmap scan report for (.+?)(?: \[host down(?:, received (\S+))?\])?$`)
+	//Host is up, received echo-reply ttl 53 (0.18s latency).
+	hostUpRegexp = regexp.MustCompile(`^Host is up(?:, received (\S+)(?: ttl (\d+))?)?`)
+	//22/tcp    open     ssh
+	normalPortRegexp = regexp.MustCompile(`^(\d+)/(\w+)\s`)
+	//995 closed tcp ports (reset)，旧版本为 995 closed ports
+	notShownRegexp = regexp.MustCompile(`^(\d+) (\S+)(?: (\w+))? ports?(?: \(([^)]*)\))?$`)
+	//All 1000 scanned ports on 192.168.1.2 are in ignored states.，旧版本为 are closed
+	allPortsRegexp = regexp.MustCompile(`^All (\d+) scanned ports on .+ are (\S+)`)
+	//Aggressive OS guesses: Linux 3.2 (95%), Linux 4.4 (94%)
+	osGuessRegexp = regexp.MustCompile(`(.+?) \((\d+)%\)(?:, |$)`)
+	//Uptime guess: 12.345 days (since Mon Apr  4 02:00:00 2022)
+	uptimeRegexp = regexp.MustCompile(`^([\d.]+) days \(since (.+)\)`)
+	//TCP Sequence Prediction: Difficulty=260 (Good luck!)
+	sequenceRegexp = regexp.MustCompile(`^Difficulty=(\d+) \((.*)\)`)
+	//TRACEROUTE (using port 80/tcp)，TRACEROUTE (using proto 1/icmp)
+	tracerouteRegexp = regexp.MustCompile(`^TRACEROUTE \(using (port|proto) (\d+)/(\w+)\)`)
+	//12  180.00 ms scanme.nmap.org (45.33.32.156)
+	hopRegexp = regexp.MustCompile(`^(\d+)\s+(\S+)(?: ms)?\s+(.+)$`)
+	//scanme.nmap.org (45.33.32.156)
+	nameAddrRegexp = regexp.MustCompile(`^(.+) \(([^()]+)\)$`)
+)
+
+// normal结果的段落
+const (
+	sectionNone = iota
+	sectionPrescript
+	sectionPostscript
+	sectionHost
+	sectionPorts
+	sectionHostScript
+	sectionTrace
+)
+
+// normalParser 逐行解析-oN的结果
+type normalParser struct {
+	result  *NmapXMLResult
+	section int
+	//端口表各列的名称和开始位置
+	columns []string
+	offsets []int
+	//当前脚本未结束（未遇到|_）
+	inScript bool
+	//操作系统识别的设备类型和cpe，在host结束时加入osclass
+	deviceType string
+	osCPE      []string
+}
+
+// ParseNormal 解析nmap -oN的结果或交互输出，包括host状态、端口、服务、脚本、忽略的端口数、操作系统、uptime、序列号和traceroute
+//
+// normal格式不区分product和version，均保存在Service.Product中，括号中的内容保存在Service.ExtraInfo中
+func ParseNormal(data []byte) (*NmapXMLResult, error) {
+	parser := &normalParser{result: newOutputResult()}
+	scanner := newLineScanner(data)
+	lineNum := 0
+	found := false
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if parseRunLine(parser.result, line) {
+			found = true
+			continue
+		}
+		if strings.HasPrefix(line, "Nmap scan report for ") {
+			found = true
+		}
+		if err := parser.parseLine(line); err != nil {
+			return nil, errors.Wrapf(ErrOutputParse, "normal line %d: %s", lineNum, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, errors.Wrap(ErrOutputParse, "not nmap normal output")
+	}
+	parser.endHost()
+	finishOutputResult(parser.result)
+	return parser.result, nil
+}
+
+func (p *normalParser) host() *Host {
+	return &p.result.Host[len(p.result.Host)-1]
+}
+
+func (p *normalParser) parseLine(line string) error {
+	if strings.HasPrefix(line, "|") {
+		p.parseScript(line)
+		return nil
+	}
+	p.inScript = false
+	switch line {
+	case "":
+		if p.section == sectionPorts || p.section == sectionTrace || p.section == sectionHostScript {
+			p.section = sectionHost
+		}
+		return nil
+	case "Pre-scan script results:":
+		p.section = sectionPrescript
+		return nil
+	case "Post-scan script results:":
+		p.endHost()
+		p.section = sectionPostscript
+		return nil
+	}
+	if m := reportRegexp.FindStringSubmatch(line); m != nil {
+		p.startHost(m[1], m[2], strings.Contains(line, "[host down"))
+		return nil
+	}
+	if p.section < sectionHost {
+		return nil
+	}
+	if p.section == sectionPorts {
+		if m := normalPortRegexp.FindStringSubmatch(line); m != nil {
+			return p.parsePort(line, m)
+		}
+		p.section = sectionHost
+	}
+	if p.section == sectionTrace {
+		p.parseHop(line)
+		return nil
+	}
+	host := p.host()
+	name, value, _ := strings.Cut(line, ": ")
+	switch {
+	case line == "Host script results:":
+		p.section = sectionHostScript
+	case strings.HasPrefix(line, "PORT ") && strings.Contains(line, "STATE"):
+		p.columns = strings.Fields(line)
+		p.offsets = p.offsets[:0]
+		for _, column := range p.columns {
+			offset := 0
+			if n := len(p.offsets); n != 0 {
+				offset = p.offsets[n-1] + len(p.columns[n-1])
+			}
+			p.offsets = append(p.offsets, offset+strings.Index(line[offset:], column))
+		}
+		p.section = sectionPorts
+	case hostUpRegexp.MatchString(line):
+		m := hostUpRegexp.FindStringSubmatch(line)
+		host.Status = Status{State: HostStateUp, Reason: m[1]}
+		host.Status.ReasonTTl, _ = strconv.Atoi(m[2])
+	case strings.HasPrefix(line, "Note: Host seems down"):
+		host.Status.State = HostStateDown
+	case name == "Not shown":
+		for _, part := range strings.Split(value, ", ") {
+			if m := notShownRegexp.FindStringSubmatch(part); m != nil {
+				p.addExtraPorts(m[2], m[1], m[3], m[4])
+			}
+		}
+	case allPortsRegexp.MatchString(line):
+		m := allPortsRegexp.FindStringSubmatch(line)
+		//新版本在下一行的Not shown中给出状态
+		if m[2] != "in" {
+			p.addExtraPorts(strings.TrimSuffix(m[2], "."), m[1], "", "")
+		}
+	case name == "MAC Address":
+		mac, vendor, _ := strings.Cut(value, " ")
+		host.Address = append(host.Address, Address{Addr: mac, AddrType: "mac", Vendor: strings.Trim(vendor, "()")})
+	case strings.HasPrefix(line, "rDNS record for "):
+		host.Hostnames = append(host.Hostnames, Hostname{Name: value, Type: HostnameTypePTR})
+	case name == "Device type":
+		p.deviceType = value
+	case name == "OS CPE":
+		p.osCPE = strings.Fields(value)
+	case name == "OS details":
+		p.addOSMatch(OSMatch{Name: value, Accuracy: 100})
+	case name == "Aggressive OS guesses":
+		for _, m := range osGuessRegexp.FindAllStringSubmatch(value, -1) {
+			accuracy, _ := strconv.Atoi(m[2])
+			p.addOSMatch(OSMatch{Name: m[1], Accuracy: accuracy})
+		}
+	case name == "Network Distance":
+		//截断的文件中可能没有值
+		if fields := strings.Fields(value); len(fields) != 0 {
+			distance, _ := strconv.Atoi(fields[0])
+			host.Distance = append(host.Distance, Distance{Value: distance})
+		}
+	case name == "Uptime guess":
+		if m := uptimeRegexp.FindStringSubmatch(value); m != nil {
+			days, _ := strconv.ParseFloat(m[1], 64)
+			host.Uptime = append(host.Uptime, Uptime{Seconds: int(days * 86400), LastBoot: m[2]})
+		}
+	case name == "TCP Sequence Prediction":
+		if m := sequenceRegexp.FindStringSubmatch(value); m != nil {
+			index, _ := strconv.Atoi(m[1])
+			host.TCPSequence = append(host.TCPSequence, TCPSequence{Index: index, Difficulty: m[2]})
+		}
+	case name == "IP ID Sequence Generation":
+		host.IpIdSequence = append(host.IpIdSequence, IpIdSequence{Class: value})
+	case tracerouteRegexp.MatchString(line):
+		m := tracerouteRegexp.FindStringSubmatch(line)
+		trace := Trace{Proto: m[3]}
+		if m[1] == "port" {
+			trace.Port, _ = strconv.Atoi(m[2])
+		}
+		host.Trace = append(host.Trace, trace)
+		p.section = sectionTrace
+	}
+	return nil
+}
+
+// startHost 开始新的host，target为 name (ip) 或 ip
+func (p *normalParser) startHost(target, reason string, down bool) {
+	p.endHost()
+	host := Host{Status: Status{State: HostStateUp}}
+	if down {
+		host.Status = Status{State: HostStateDown, Reason: reason}
+	}
+	if m := nameAddrRegexp.FindStringSubmatch(target); m != nil {
+		host.Address = []Address{hostAddress(m[2])}
+		host.Hostnames = []Hostname{{Name: m[1], Type: HostnameTypeUser}}
+	} else {
+		host.Address = []Address{hostAddress(target)}
+	}
+	p.result.Host = append(p.result.Host, host)
+	p.section = sectionHost
+}
+
+// endHost 将设备类型和cpe加入准确率最高的osmatch
+func (p *normalParser) endHost() {
+	if len(p.result.Host) != 0 && (p.deviceType != "" || len(p.osCPE) != 0) {
+		host := p.host()
+		if len(host.OS) != 0 && len(host.OS[0].OSMatch) != 0 {
+			osMatch := &host.OS[0].OSMatch[0]
+			osMatch.OSClass = append(osMatch.OSClass, OSClass{Type: p.deviceType, Accuracy: osMatch.Accuracy, CPE: p.osCPE})
+		}
+	}
+	p.deviceType = ""
+	p.osCPE = nil
+	p.section = sectionNone
+}
+
+func (p *normalParser) addOSMatch(osMatch OSMatch) {
+	host := p.host()
+	if len(host.OS) == 0 {
+		host.OS = []OS{{}}
+	}
+	host.OS[0].OSMatch = append(host.OS[0].OSMatch, osMatch)
+}
+
+func (p *normalParser) addExtraPorts(state, count, proto, reason string) {
+	host := p.host()
+	if len(host.Ports) == 0 {
+		host.Ports = []Ports{{}}
+	}
+	extraPorts := ExtraPorts{State: PortState(state)}
+	extraPorts.Count, _ = strconv.Atoi(count)
+	if reason != "" {
+		extraPorts.ExtraReasons = []ExtraReasons{{Reason: reason, Count: extraPorts.Count, Proto: PortProtocol(proto)}}
+	}
+	host.Ports[0].ExtraPorts = append(host.Ports[0].ExtraPorts, extraPorts)
+}
+
+// parsePort 按表头的位置解析端口行，列为PORT STATE SERVICE，可能有REASON、VERSION
+func (p *normalParser) parsePort(line string, m []string) error {
+	id, err := strconv.ParseUint(m[1], 10, 16)
+	if err != nil {
+		return errors.Errorf("invalid port %q", line)
+	}
+	port := Port{PortId: uint16(id), Protocol: PortProtocol(m[2])}
+	for i, column := range p.columns {
+		start := p.offsets[i]
+		if start >= len(line) {
+			break
+		}
+		end := len(line)
+		if i+1 < len(p.offsets) && p.offsets[i+1] < end {
+			end = p.offsets[i+1]
+		}
+		value := strings.TrimSpace(line[start:end])
+		switch column {
+		case "STATE":
+			port.State.State = PortState(value)
+		case "SERVICE":
+			if tunnel, service, ok := strings.Cut(value, "/"); ok {
+				port.Service.Tunnel = tunnel
+				value = service
+			}
+			//?表示根据端口号猜测的服务
+			if strings.HasSuffix(value, "?") {
+				value = strings.TrimSuffix(value, "?")
+				port.Service.Method = "table"
+				port.Service.Conf = Confidence3
+			}
+			port.Service.Name = value
+		case "REASON":
+			fields := strings.Fields(value)
+			if len(fields) != 0 {
+				port.State.Reason = fields[0]
+			}
+			if len(fields) == 3 && fields[1] == "ttl" {
+				port.State.ReasonTTL, _ = strconv.Atoi(fields[2])
+			}
+		case "VERSION":
+			splitServiceVersion(&port.Service, value)
+		}
+	}
+	host := p.host()
+	if len(host.Ports) == 0 {
+		host.Ports = []Ports{{}}
+	}
+	host.Ports[0].Port = append(host.Ports[0].Port, port)
+	return nil
+}
+
+// parseScript 解析脚本输出，| 开始的行为脚本的一行，|_ 为脚本的最后一行
+//
+// 第一行为 | id: output，之后的行为output的后续行
+func (p *normalParser) parseScript(line string) {
+	scripts := p.scripts()
+	if scripts == nil {
+		return
+	}
+	content := ""
+	if len(line) > 2 {
+		content = line[2:]
+	}
+	if p.inScript && len(*scripts) != 0 {
+		script := &(*scripts)[len(*scripts)-1]
+		script.Output += "\n" + content
+	} else {
+		id, output, _ := strings.Cut(content, ":")
+		*scripts = append(*scripts, Script{Id: id, Output: strings.TrimPrefix(output, " ")})
+	}
+	p.inScript = !strings.HasPrefix(line, "|_")
+}
+
+// scripts 当前段落的脚本
+func (p *normalParser) scripts() *[]Script {
+	switch p.section {
+	case sectionPrescript:
+		return &p.result.Prescript
+	case sectionPostscript:
+		return &p.result.Postscript
+	case sectionHostScript:
+		return &p.host().HostScript
+	case sectionPorts:
+		host := p.host()
+		if len(host.Ports) != 0 && len(host.Ports[0].Port) != 0 {
+			ports := host.Ports[0].Port
+			return &ports[len(ports)-1].Script
+		}
+	}
+	return nil
+}
+
+// parseHop 解析traceroute的一跳，跳过表头和省略的跳
+func (p *normalParser) parseHop(line string) {
+	m := hopRegexp.FindStringSubmatch(line)
+	//省略的跳，如 3   ... 10
+	if m == nil || m[2] == "..." {
+		return
+	}
+	hop := Hop{Ipaddr: m[3]}
+	hop.TTL, _ = strconv.Atoi(m[1])
+	if m[2] == "--" {
+		hop.RTT = RTTUnknown
+	} else {
+		rtt, _ := strconv.ParseFloat(m[2], 64)
+		hop.RTT = RTT(rtt)
+	}
+	if am := nameAddrRegexp.FindStringSubmatch(m[3]); am != nil {
+		hop.Host = am[1]
+		hop.Ipaddr = am[2]
+	}
+	host := p.host()
+	trace := &host.Trace[len(host.Trace)-1]
+	trace.Hop = append(trace.Hop, hop)
+}
@@ -0,0 +1,159 @@
+package nmap
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// -oG、-oN结果中与xml共用的开始、结束信息
+
+var (
+	//# Nmap 7.92 scan initiated Mon Apr 18 10:00:00 2022 as: nmap -sV -oG - scanme.nmap.org
+	initiatedRegexp = regexp.MustCompile(`^# Nmap (\S+) scan initiated (.+?) as: (.*)$`)
+	//Starting Nmap 7.92 ( https://nmap.org ) at 2022-04-18 10:00 CST
+	startingRegexp = regexp.MustCompile(`^Starting Nmap (\S+) \( \S+ \) at (\d{4}-\d{2}-\d{2} \d{2}:\d{2})`)
+	//# Nmap done at Mon Apr 18 10:00:10 2022 -- 1 IP address (1 host up) scanned in 10.50 seconds
+	//Nmap done: 1 IP address (1 host up) scanned in 10.50 seconds
+	doneRegexp = regexp.MustCompile(`^(?:# )?Nmap done(?: at (.+?) --|:) (\d+) IP address(?:es)? \((\d+) hosts? up\) scanned in ([\d.]+) seconds`)
+)
+
+// nmap输出的时间格式，如 Mon Apr 18 10:00:00 2022
+var outputTimeLayouts = []string{"Mon Jan 02 15:04:05 2006", "Mon Jan _2 15:04:05 2006"}
+
+// newOutputResult -oG、-oN结果的NmapXMLResult，scanner为nmap
+func newOutputResult() *NmapXMLResult {
+	return &NmapXMLResult{Scanner: "nmap"}
+}
+
+// parseOutputTime 解析nmap输出的时间，按本地时区
+func parseOutputTime(value string) (int64, bool) {
+	for _, layout := range outputTimeLayouts {
+		t, err := time.ParseInLocation(layout, value, time.Local)
+		if err == nil {
+			return t.Unix(), true
+		}
+	}
+	return 0, false
+}
+
+// parseRunLine 解析开始和结束行，不是开始或结束行时返回false
+func parseRunLine(result *NmapXMLResult, line string) bool {
+	if m := initiatedRegexp.FindStringSubmatch(line); m != nil {
+		result.Version = m[1]
+		result.StartStr = m[2]
+		result.Start, _ = parseOutputTime(m[2])
+		result.Args = m[3]
+		return true
+	}
+	if m := startingRegexp.FindStringSubmatch(line); m != nil {
+		result.Version = m[1]
+		if t, err := time.ParseInLocation("2006-01-02 15:04", m[2], time.Local); err == nil {
+			result.Start = t.Unix()
+			result.StartStr = t.Format(outputTimeLayouts[0])
+		}
+		return true
+	}
+	if m := doneRegexp.FindStringSubmatch(line); m != nil {
+		finished := &result.RunStats.Finished
+		finished.Exit = "success"
+		finished.TimeStr = m[1]
+		finished.Time, _ = parseOutputTime(m[1])
+		elapsed, _ := strconv.ParseFloat(m[4], 32)
+		finished.Elapsed = float32(elapsed)
+		if finished.Time == 0 && result.Start != 0 {
+			finished.Time = result.Start + int64(elapsed)
+			finished.TimeStr = time.Unix(finished.Time, 0).Format(outputTimeLayouts[0])
+		}
+		total, _ := strconv.Atoi(m[2])
+		up, _ := strconv.Atoi(m[3])
+		result.RunStats.Hosts = Hosts{Up: up, Down: total - up, Total: total}
+		finished.Summary = fmt.Sprintf("Nmap done at %s; %s IP address%s (%s host%s up) scanned in %s seconds",
+			finished.TimeStr, m[2], plural(total, "es"), m[3], plural(up, "s"), m[4])
+		return true
+	}
+	return false
+}
+
+// finishOutputResult 没有结束行时（输出不完整），由已解析的host生成runstats
+func finishOutputResult(result *NmapXMLResult) {
+	if result.RunStats.Finished.Exit != "" {
+		return
+	}
+	var up, down int
+	for _, host := range result.Host {
+		switch host.Status.State {
+		case HostStateUp:
+			up++
+		case HostStateDown:
+			down++
+		}
+	}
+	result.RunStats = RunStats{
+		Finished: Finished{
+			Summary: fmt.Sprintf("Nmap output incomplete; %d IP addresses (%d hosts up) recovered", up+down, up),
+			Exit:    "error",
+		},
+		Hosts:      Hosts{Up: up, Down: down, Total: up + down},
+		Incomplete: true,
+	}
+}
+
+func plural(count int, suffix string) string {
+	if count == 1 {
+		return ""
+	}
+	return suffix
+}
+
+// newLineScanner 按行读取，单行最长16MB
+func newLineScanner(data []byte) *bufio.Scanner {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 16*1024*1024)
+	return scanner
+}
+
+// hostAddress ip地址的Address
+func hostAddress(addr string) Address {
+	addrType := "ipv4"
+	if strings.Contains(addr, ":") {
+		addrType = "ipv6"
+	}
+	return Address{Addr: addr, AddrType: addrType}
+}
+
+// splitServiceVersion 将 product version (extrainfo) 中的extrainfo分离，product和version无法区分，均作为product
+func splitServiceVersion(service *Service, value string) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return
+	}
+	if strings.HasSuffix(value, ")") {
+		if i := matchingParen(value); i > 0 {
+			service.ExtraInfo = value[i+1 : len(value)-1]
+			value = strings.TrimSpace(value[:i])
+		}
+	}
+	service.Product = value
+}
+
+// matchingParen 末尾的)对应的(的位置
+func matchingParen(value string) int {
+	depth := 0
+	for i := len(value) - 1; i >= 0; i-- {
+		switch value[i] {
+		case ')':
+			depth++
+		case '(':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
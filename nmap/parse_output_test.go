@@ -0,0 +1,137 @@
+package nmap
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func loadOutput(t *testing.T, fileName string, parse func([]byte) (*NmapXMLResult, error)) *NmapXMLResult {
+	t.Helper()
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+// checkOpenPorts 与xml结果的开放端口、服务一致
+func checkOpenPorts(t *testing.T, result *NmapXMLResult) {
+	t.Helper()
+	expected := loadXML(t, "testdata/scanme.xml").OpenPorts()
+	openPorts := result.OpenPorts()
+	if len(openPorts) != len(expected) {
+		t.Fatalf("expected %d open ports, but got %d", len(expected), len(openPorts))
+	}
+	for i, hostPort := range openPorts {
+		want := expected[i]
+		if hostPort.Host.IP() != want.Host.IP() || hostPort.Port.PortId != want.Port.PortId || hostPort.Port.Protocol != want.Port.Protocol ||
+			hostPort.Port.Service.Name != want.Port.Service.Name || hostPort.Port.Service.Tunnel != want.Port.Service.Tunnel ||
+			hostPort.Port.Service.ExtraInfo != want.Port.Service.ExtraInfo {
+			t.Errorf("expected %s %+v, but got %s %+v", want.Host.IP(), *want.Port, hostPort.Host.IP(), *hostPort.Port)
+		}
+	}
+}
+
+func TestParseGrepable(t *testing.T) {
+	result := loadOutput(t, "testdata/scanme.gnmap", ParseGrepable)
+	checkOpenPorts(t, result)
+	if result.Version != "7.92" || result.Args == "" || result.Start == 0 {
+		t.Errorf("unexpected run info %s %q %d", result.Version, result.Args, result.Start)
+	}
+	if hosts := result.RunStats.Hosts; hosts.Up != 2 || hosts.Down != 1 || result.RunStats.Finished.Elapsed != 60 {
+		t.Errorf("unexpected runstats %+v", result.RunStats)
+	}
+	if len(result.Host) != 3 || len(result.Hosts().Up()) != 2 {
+		t.Fatalf("expected 3 hosts with 2 up, but got %d", len(result.Host))
+	}
+	scanme := result.Hosts().ByIP("45.33.32.156")
+	if scanme == nil || scanme.Names()[0] != "scanme.nmap.org" || scanme.Ports[0].ExtraPorts[0].Count != 995 ||
+		scanme.OS[0].OSMatch[0].Name != "Linux 4.15 - 5.6" || scanme.TCPSequence[0].Index != 261 {
+		t.Errorf("unexpected host %+v", scanme)
+	}
+	ssh := scanme.FindPort("tcp", 22)
+	if ssh.Service.Product != "OpenSSH 6.6.1p1 Ubuntu 2ubuntu2.13" {
+		t.Errorf("unexpected product %q", ssh.Service.Product)
+	}
+	if _, err := ParseGrepable([]byte("Host: 1.1.1.1 ()\tPorts: 80/open\n")); !errors.Is(err, ErrOutputParse) {
+		t.Errorf("expected ErrOutputParse, but got %v", err)
+	}
+	if _, err := ParseGrepable([]byte("hello\n")); !errors.Is(err, ErrOutputParse) {
+		t.Errorf("expected ErrOutputParse, but got %v", err)
+	}
+}
+
+func TestParseNormal(t *testing.T) {
+	result := loadOutput(t, "testdata/scanme.nmap", ParseNormal)
+	checkOpenPorts(t, result)
+	if len(result.Host) != 2 || result.RunStats.Hosts.Up != 2 || result.RunStats.Finished.TimeStr != "Mon Apr 18 10:01:00 2022" {
+		t.Errorf("unexpected runstats %+v", result.RunStats)
+	}
+	scanme := result.Hosts().ByIP("45.33.32.156")
+	if scanme.Status.Reason != "echo-reply" || scanme.Status.ReasonTTl != 53 || scanme.Ports[0].ExtraPorts[0].ExtraReasons[0].Reason != "reset" {
+		t.Errorf("unexpected host status %+v", scanme.Status)
+	}
+	ssh := scanme.FindPort("tcp", 22)
+	expected := loadXML(t, "testdata/scanme.xml").Hosts().ByIP("45.33.32.156")
+	sshScript := ssh.FindScript("ssh-hostkey")
+	expectedScript := expected.PortList()[0].FindScript("ssh-hostkey")
+	if sshScript == nil || sshScript.Output != expectedScript.Output || ssh.State.ReasonTTL != 53 {
+		t.Errorf("unexpected ssh port %+v", ssh)
+	}
+	https := scanme.FindPort("tcp", 443)
+	if cert := https.FindScript("ssl-cert"); cert == nil || len(https.Script) != 1 {
+		t.Errorf("expected ssl-cert script only, but got %+v", https.Script)
+	}
+	elite := scanme.FindPort("tcp", 31337)
+	if elite.Service.Name != "Elite" || elite.Service.Method != "table" || elite.State.State != "filtered" {
+		t.Errorf("unexpected port %+v", elite)
+	}
+	osMatch := bestOSMatch(scanme)
+	if osMatch == nil || osMatch.Name != "Linux 4.15 - 5.6" || osMatch.Accuracy != 95 || len(osMatch.OSClass[0].CPE) != 2 || len(scanme.OS[0].OSMatch) != 2 {
+		t.Errorf("unexpected os %+v", scanme.OS)
+	}
+	if scanme.Uptime[0].LastBoot != "Fri Apr  8 07:17:28 2022" || scanme.Distance[0].Value != 11 || scanme.TCPSequence[0].Difficulty != "Good luck!" {
+		t.Errorf("unexpected host info %+v %+v %+v", scanme.Uptime, scanme.Distance, scanme.TCPSequence)
+	}
+	if trace := scanme.Trace[0]; trace.Port != 80 || len(trace.Hop) != 3 || trace.Hop[2].Host != "scanme.nmap.org" || trace.Hop[2].RTT != 154.33 {
+		t.Errorf("unexpected trace %+v", trace)
+	}
+	if len(scanme.HostScript) != 1 || scanme.HostScript[0].Output != expected.HostScript[0].Output {
+		t.Errorf("unexpected host script %+v", scanme.HostScript)
+	}
+	fileserver := result.Hosts().ByIP("192.168.1.10")
+	if fileserver.MAC() != "00:0C:29:3E:5A:11" || fileserver.Names()[0] != "fileserver.corp.example" || bestOSMatch(fileserver).Accuracy != 100 {
+		t.Errorf("unexpected host %+v", fileserver)
+	}
+	if len(result.Postscript) != 1 || result.Postscript[0].Output != loadXML(t, "testdata/scanme.xml").Postscript[0].Output {
+		t.Errorf("unexpected postscript %+v", result.Postscript)
+	}
+}
+
+func TestParseNormalMalformed(t *testing.T) {
+	result, err := ParseNormal([]byte("Nmap scan report for 10.0.0.1\nHost is up.\nNetwork Distance: \n"))
+	if err != nil || len(result.Host) != 1 || len(result.Host[0].Distance) != 0 {
+		t.Fatalf("unexpected result %+v, %v", result, err)
+	}
+	//截断的文件，以及每行去掉:后的值，不能panic
+	data, err := os.ReadFile("testdata/scanme.nmap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(string(data), "\n")
+	for i := range lines {
+		_, _ = ParseNormal([]byte(strings.Join(lines[:i], "\n")))
+		malformed := append([]string(nil), lines...)
+		if name, _, ok := strings.Cut(lines[i], ":"); ok {
+			malformed[i] = name + ": "
+		}
+		_, _ = ParseNormal([]byte(strings.Join(malformed, "\n")))
+	}
+}
@@ -0,0 +1,139 @@
+package nmap
+
+import (
+	"context"
+	"strconv"
+	"sync"
+)
+
+// Pipeline 两阶段扫描，先运行发现扫描（如-sn或-sS --top-ports 100），
+// 再只对存活的host和开放的端口运行深度扫描（如-sV -sC -O），所有结果合并为一个
+//
+//	discovery := nmap.NewNmap().AddsS().Addtopports(100).AddTargets("192.168.1.0/24")
+//	deep := nmap.NewNmap().AddsV().AddsC().AddO()
+//	result, err := nmap.NewPipeline(discovery, deep).Run(ctx)
+type Pipeline struct {
+	//发现扫描，需要指定目标
+	Discovery *nmap
+	//深度扫描的模板，不需要指定目标和端口，每批host复制一份参数，并增加-Pn、-p和目标
+	//SaveXmlRaw时每个深度扫描写入ResultName-序号.xml，序号从1开始，与Scans的顺序一致
+	Deep *nmap
+	//每个深度扫描的host数，为1时每个host单独扫描，为0时开放端口相同的host合并为一个扫描
+	BatchSize int
+	//同时运行的深度扫描数，小于1时为1
+	Concurrency int
+}
+
+// PipelineScan 一次深度扫描
+type PipelineScan struct {
+	Args    []string `json:"args"`
+	Targets []string `json:"targets"`
+	//-p的值，发现扫描没有扫描端口时为空
+	Ports  string  `json:"ports"`
+	Result *Result `json:"result"`
+	Err    error   `json:"-"`
+}
+
+// PipelineResult 两阶段扫描的结果
+type PipelineResult struct {
+	//发现扫描和所有深度扫描合并后的结果，深度扫描的端口状态和服务识别优先
+	XML        *NmapXMLResult  `json:"xml"`
+	Provenance Provenance      `json:"provenance"`
+	Discovery  *Result         `json:"discovery"`
+	Scans      []*PipelineScan `json:"scans"`
+}
+
+// NewPipeline 创建两阶段扫描，discovery为发现扫描，deep为深度扫描的模板
+func NewPipeline(discovery, deep *nmap) *Pipeline {
+	return &Pipeline{Discovery: discovery, Deep: deep}
+}
+
+// Plan 根据发现扫描的结果生成深度扫描
+//
+// 发现扫描扫描了端口时，按开放端口分组，没有开放端口的host忽略；没有扫描端口时（如-sn），对所有存活的host使用默认端口
+func (p *Pipeline) Plan(discovery *NmapXMLResult) []*nmap {
+	_, scans := p.plan(discovery)
+	return scans
+}
+
+func (p *Pipeline) plan(discovery *NmapXMLResult) ([]*followUpGroup, []*nmap) {
+	groups := followUpGroups(discovery, len(discovery.ScanInfo) == 0, p.BatchSize)
+	scans := make([]*nmap, 0, len(groups))
+	for i, g := range groups {
+		scan := p.Deep.clone()
+		if scan.BinPath == "" {
+			scan.BinPath = p.Discovery.BinPath
+		}
+		//同时运行的深度扫描不写入同一个xml
+		scan.exportOption.ResultName += "-" + strconv.Itoa(i+1)
+		scans = append(scans, g.apply(scan))
+	}
+	return groups, scans
+}
+
+// Run 通过指定context或使用默认context 运行发现扫描和深度扫描
+//
+// 发现扫描出错时不运行深度扫描；深度扫描出错时其他扫描继续运行，返回合并后的结果和第一个错误，
+// 每个扫描的错误见PipelineScan.Err
+func (p *Pipeline) Run(pctx ...context.Context) (*PipelineResult, error) {
+	ctx, err := checkCtx(pctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.Deep.Validate(); err != nil {
+		return nil, err
+	}
+	discovery, err := p.Discovery.Run(ctx)
+	result := &PipelineResult{Discovery: discovery}
+	if err != nil || discovery.XML == nil {
+		return result, err
+	}
+	groups, scans := p.plan(discovery.XML)
+	for i, scan := range scans {
+		result.Scans = append(result.Scans, &PipelineScan{Args: scan.Args, Targets: groups[i].targets, Ports: groups[i].ports})
+	}
+
+	concurrency := p.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, scan := range scans {
+		sem <- struct{}{}
+		//已取消时不再运行剩余的扫描
+		if ctx.Err() != nil {
+			<-sem
+			result.Scans[i].Err = ctxErr(ctx.Err())
+			continue
+		}
+		wg.Add(1)
+		go func(scan *nmap, s *PipelineScan) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			s.Result, s.Err = scan.Run(ctx)
+		}(scan, result.Scans[i])
+	}
+	wg.Wait()
+
+	xmlResults := []*NmapXMLResult{discovery.XML}
+	for _, s := range result.Scans {
+		if s.Err != nil && err == nil {
+			err = s.Err
+		}
+		if s.Result != nil && s.Result.XML != nil {
+			xmlResults = append(xmlResults, s.Result.XML)
+		}
+	}
+	result.XML, result.Provenance = Merge(xmlResults...)
+	return result, err
+}
+
+// clone 复制参数和配置，多个扫描互不影响
+func (receiver *nmap) clone() *nmap {
+	n := *receiver
+	n.Args = append([]string(nil), receiver.Args...)
+	n.progressFuncs = append([]ProgressFunc(nil), receiver.progressFuncs...)
+	n.errs = append([]error(nil), receiver.errs...)
+	return &n
+}
@@ -0,0 +1,124 @@
+package nmap
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestPipeline(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake nmap is a shell script")
+	}
+	testdata, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	//记录每次运行的参数，-sn输出发现结果，其他输出端口扫描结果
+	script := `#!/bin/sh
+echo "$@" >> ` + dir + `/args
+case " $* " in
+*" -sn "*) cat ` + testdata + `/discovery.xml ;;
+*" -sU "*|*" -6 "*) cat ` + dir + `/mixed.xml ;;
+*) cat ` + testdata + `/scanme.xml ;;
+esac
+`
+	binPath := filepath.Join(dir, "nmap")
+	if err := os.WriteFile(binPath, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	//同时有tcp和udp端口的IPv4 host，以及IPv6 host
+	mixed := `<?xml version="1.0" encoding="UTF-8"?>
+<nmaprun scanner="nmap" args="nmap -sT -sU -p 22,53,80 -oX - 10.0.0.1 2001:db8::1" start="1650243600" version="7.92" xmloutputversion="1.05">
+<scaninfo type="connect" protocol="tcp" numservices="3" services="22,53,80"/>
+<scaninfo type="udp" protocol="udp" numservices="3" services="22,53,80"/>
+<host><status state="up" reason="user-set" reason_ttl="0"/>
+<address addr="10.0.0.1" addrtype="ipv4"/>
+<ports><port protocol="tcp" portid="22"><state state="open" reason="syn-ack" reason_ttl="0"/></port>
+<port protocol="udp" portid="53"><state state="open" reason="udp-response" reason_ttl="0"/></port>
+</ports>
+</host>
+<host><status state="up" reason="user-set" reason_ttl="0"/>
+<address addr="2001:db8::1" addrtype="ipv6"/>
+<ports><port protocol="tcp" portid="80"><state state="open" reason="syn-ack" reason_ttl="0"/></port>
+</ports>
+</host>
+<runstats><finished time="1650243610" elapsed="10.00" exit="success"/><hosts up="2" down="0" total="2"/></runstats>
+</nmaprun>
+`
+	if err := os.WriteFile(filepath.Join(dir, "mixed.xml"), []byte(mixed), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := NewConfig()
+
+	cases := []struct {
+		name      string
+		discovery *nmap
+		deep      *nmap
+		batchSize int
+		args      []string
+		openPorts int
+	}{
+		{"ports", NewNmap(cfg).AddsT().Addtopports(100).AddTargets("scanme.nmap.org"), NewNmap(cfg).AddsV(), 0, []string{
+			"-sT --top-ports 100 scanme.nmap.org -oX -",
+			"-sV -Pn -p T:135,139,445 192.168.1.10 -oX -",
+			"-sV -Pn -p T:22,80,443,9929 45.33.32.156 -oX -",
+		}, 7},
+		{"live hosts", NewNmap(cfg).Addsn().AddTargets("192.168.1.0/29"), NewNmap(cfg).AddsV(), 2, []string{
+			"-sn 192.168.1.0/29 -oX -",
+			"-sV -Pn 192.168.1.1 192.168.1.10 -oX -",
+			"-sV -Pn 192.168.1.5 -oX -",
+		}, 7},
+		//deep已有-sT时不增加-sS，IPv6 host增加-6
+		{"tcp udp ipv6", AddArgs(NewNmap(cfg).AddsT().AddsU().Addp("22,53,80").AddTargets("10.0.0.1", "2001:db8::1"), "--privileged"),
+			AddArgs(NewNmap(cfg).AddsT().AddsV(), "--privileged"), 0, []string{
+				"-sT -sU -p 22,53,80 10.0.0.1 2001:db8::1 --privileged -oX -",
+				"-sT -sV --privileged -Pn -6 -p T:80 2001:db8::1 -oX -",
+				"-sT -sV --privileged -Pn -sU -p T:22,U:53 10.0.0.1 -oX -",
+			}, 3},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			os.Remove(filepath.Join(dir, "args"))
+			c.discovery.BinPath = binPath
+			//每个深度扫描写入单独的xml
+			sink := FileSink{Dir: t.TempDir()}
+			c.discovery.Sink, c.deep.Sink = sink, sink
+			pipeline := NewPipeline(c.discovery, c.deep)
+			pipeline.BatchSize = c.batchSize
+			pipeline.Concurrency = 2
+			result, err := pipeline.Run()
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(filepath.Join(dir, "args"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			args := strings.Split(strings.TrimSpace(string(data)), "\n")
+			sort.Strings(args[1:])
+			if !reflect.DeepEqual(args, c.args) {
+				t.Errorf("expected %q, but got %q", c.args, args)
+			}
+			if len(result.Scans) != len(c.args)-1 || result.XML == nil || len(result.XML.OpenPorts()) != c.openPorts {
+				t.Errorf("expected merged result of %d scans", len(c.args)-1)
+			}
+			files, err := filepath.Glob(filepath.Join(sink.Dir, "*.xml"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []string{"Result-1.xml", "Result-2.xml", "Result.xml"}
+			for i, file := range files {
+				files[i] = filepath.Base(file)
+			}
+			if !reflect.DeepEqual(files, want) {
+				t.Errorf("expected %v, but got %v", want, files)
+			}
+		})
+	}
+}
@@ -70,7 +70,8 @@ func (receiver *nmap) Addr() *nmap {
 //Scans the <n> highest-ratio ports found in nmap-services file after excluding all ports specified by --exclude-ports. <n> must be 1 or greater.
 func (receiver *nmap) Addtopports(number int) *nmap {
 	if number < 1 {
-		panic("number must be 1 or greater")
+		receiver.setErr(&ValidationError{Kind: ValidationRange, Option: "--top-ports", Message: "number must be 1 or greater"})
+		return receiver
 	}
 	return AddArgs(receiver, "--top-ports", strconv.Itoa(number))
 }
@@ -82,7 +83,8 @@ func (receiver *nmap) Addtopports(number int) *nmap {
 //Scans all ports in nmap-services file with a ratio greater than the one given. <ratio> must be between 0.0 and 1.0.
 func (receiver *nmap) Addportratio(ratio float32) *nmap {
 	if ratio < 0 || ratio > 1 {
-		panic("<ratio> must be between 0.0 and 1.0.")
+		receiver.setErr(&ValidationError{Kind: ValidationRange, Option: "--port-ratio", Message: "<ratio> must be between 0.0 and 1.0."})
+		return receiver
 	}
 	return AddArgs(receiver, "--port-ratio", fmt.Sprintf("%.1f", ratio))
 }
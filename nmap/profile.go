@@ -0,0 +1,346 @@
+package nmap
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed profiles/builtin.yaml
+var builtinProfiles []byte
+
+// Profile 命名的扫描配置，包括扫描参数和导出配置，可保存为JSON或YAML文件
+//
+//	name: quick-web
+//	version: 2
+//	extends: quick
+//	remove: [-F]
+//	args: [-sV, -p, "80,443,8080"]
+//	config:
+//	  result_name: quick-web
+type Profile struct {
+	Name string `json:"name"`
+	//profile的版本，由使用者维护
+	Version     int    `json:"version,omitempty"`
+	Description string `json:"description,omitempty"`
+	//继承的profile名称
+	Extends string `json:"extends,omitempty"`
+	//扫描参数，继承时覆盖父profile中的同名参数（如-T），目标、端口、脚本、脚本参数和排除的目标整体覆盖
+	Args []string `json:"args,omitempty"`
+	//继承时删除父profile中的参数，如[-sS, -O]
+	Remove  []string `json:"remove,omitempty"`
+	BinPath string   `json:"bin_path,omitempty"`
+	//如 5s，为空时为默认值
+	GracePeriod string `json:"grace_period,omitempty"`
+	//导出配置，继承时只覆盖指定的字段，为nil时为NewConfig()
+	Config *config `json:"config,omitempty"`
+	//文件中的config，用于继承时只覆盖指定的字段
+	configRaw json.RawMessage
+}
+
+func (p *Profile) UnmarshalJSON(data []byte) error {
+	type profile Profile
+	var raw struct {
+		Config json.RawMessage `json:"config"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, (*profile)(p)); err != nil {
+		return err
+	}
+	//未指定的字段为默认值
+	if len(raw.Config) != 0 && string(raw.Config) != "null" {
+		p.configRaw = raw.Config
+		p.Config = NewConfig()
+		if err := json.Unmarshal(raw.Config, p.Config); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewProfile 保存nmap的参数和导出配置
+func NewProfile(name string, scanner *nmap) *Profile {
+	cfg := scanner.exportOption
+	profile := &Profile{
+		Name:    name,
+		Args:    append([]string(nil), scanner.Args...),
+		BinPath: scanner.BinPath,
+		Config:  &cfg,
+	}
+	if scanner.GracePeriod != defaultGracePeriod {
+		profile.GracePeriod = scanner.GracePeriod.String()
+	}
+	return profile
+}
+
+// Nmap 根据profile创建nmap，targets为追加的扫描目标，profile有继承时需要先通过ProfileLibrary.Get解析
+func (p *Profile) Nmap(targets ...string) (*nmap, error) {
+	if p.Extends != "" {
+		return nil, errors.Errorf("profile %q extends %q, resolve it with ProfileLibrary.Get", p.Name, p.Extends)
+	}
+	cfg := NewConfig()
+	if p.Config != nil {
+		*cfg = *p.Config
+	}
+	n := NewNmap(cfg)
+	n.Args = append(append(n.Args, p.Args...), targets...)
+	n.BinPath = p.BinPath
+	if p.GracePeriod != "" {
+		grace, err := time.ParseDuration(p.GracePeriod)
+		if err != nil {
+			return nil, errors.Wrapf(err, "profile %q grace_period", p.Name)
+		}
+		n.GracePeriod = grace
+	}
+	return n, nil
+}
+
+// WriteJSON 以JSON格式写入profile
+func (p *Profile) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(p)
+}
+
+// WriteYAML 以YAML格式写入profile，字段名与JSON相同
+func (p *Profile) WriteYAML(w io.Writer) error {
+	return writeYAML(w, p)
+}
+
+// ParseProfiles 解析JSON或YAML格式的profile，内容可以是一个profile或profile的列表
+func ParseProfiles(data []byte) ([]*Profile, error) {
+	//YAML转换为JSON后解析，字段名与JSON相同
+	var value any
+	if err := yaml.Unmarshal(data, &value); err != nil {
+		return nil, errors.Wrap(err, "parse profile")
+	}
+	jsonData, err := json.Marshal(value)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse profile")
+	}
+	var profiles []*Profile
+	if _, ok := value.([]any); ok {
+		err = json.Unmarshal(jsonData, &profiles)
+	} else {
+		profile := &Profile{}
+		err = json.Unmarshal(jsonData, profile)
+		profiles = []*Profile{profile}
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "parse profile")
+	}
+	for i, profile := range profiles {
+		if profile == nil || profile.Name == "" {
+			return nil, errors.Errorf("profile %d has no name", i)
+		}
+	}
+	return profiles, nil
+}
+
+// SaveProfiles 保存profile到文件，扩展名为.json时为JSON格式，否则为YAML格式，多个profile保存为列表
+func SaveProfiles(path string, profiles ...*Profile) error {
+	var value any = profiles
+	if len(profiles) == 1 {
+		value = profiles[0]
+	}
+	var buf bytes.Buffer
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		encoder := json.NewEncoder(&buf)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(value); err != nil {
+			return err
+		}
+	} else if err := writeYAML(&buf, value); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// writeYAML 先转换为JSON，保持JSON的字段名和顺序，再转换为YAML
+func writeYAML(w io.Writer, value any) error {
+	jsonData, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(jsonData, &node); err != nil {
+		return err
+	}
+	blockStyle(&node)
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// blockStyle JSON解析得到的节点为flow风格，转换为YAML的block风格，字符串只在需要时加引号
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+// ProfileLibrary 按名称管理profile，支持继承，同名的profile后添加的覆盖先添加的
+type ProfileLibrary struct {
+	mu       sync.RWMutex
+	profiles map[string]*Profile
+}
+
+// NewProfileLibrary 创建包含内置profile的ProfileLibrary，内置profile见BuiltinProfiles
+func NewProfileLibrary() (*ProfileLibrary, error) {
+	profiles, err := BuiltinProfiles()
+	if err != nil {
+		return nil, err
+	}
+	library := &ProfileLibrary{profiles: make(map[string]*Profile)}
+	library.Add(profiles...)
+	return library, nil
+}
+
+// BuiltinProfiles 内置的profile：quick、quick-web、discovery、service、full-tcp、udp-top、external-weekly、pci-internal
+func BuiltinProfiles() ([]*Profile, error) {
+	return ParseProfiles(builtinProfiles)
+}
+
+// Add 添加profile，已存在时覆盖
+func (l *ProfileLibrary) Add(profiles ...*Profile) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, profile := range profiles {
+		l.profiles[profile.Name] = profile
+	}
+}
+
+// Load 添加JSON或YAML格式的profile
+func (l *ProfileLibrary) Load(data []byte) error {
+	profiles, err := ParseProfiles(data)
+	if err != nil {
+		return err
+	}
+	l.Add(profiles...)
+	return nil
+}
+
+// LoadFile 添加文件中的profile
+func (l *ProfileLibrary) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return errors.Wrap(l.Load(data), path)
+}
+
+// LoadDir 添加目录中所有.json、.yaml和.yml文件中的profile，按文件名顺序
+func (l *ProfileLibrary) LoadDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".json", ".yaml", ".yml":
+			if err := l.LoadFile(filepath.Join(dir, entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Names 所有profile的名称
+func (l *ProfileLibrary) Names() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	names := make([]string, 0, len(l.profiles))
+	for name := range l.profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get 获取解析继承后的profile，不存在时返回ErrUnknownProfile
+func (l *ProfileLibrary) Get(name string) (*Profile, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.resolve(name, nil)
+}
+
+// Nmap 根据profile创建nmap，targets为追加的扫描目标
+func (l *ProfileLibrary) Nmap(name string, targets ...string) (*nmap, error) {
+	profile, err := l.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return profile.Nmap(targets...)
+}
+
+// resolve 按继承关系合并参数和配置，chain用于检查循环继承
+func (l *ProfileLibrary) resolve(name string, chain []string) (*Profile, error) {
+	profile, ok := l.profiles[name]
+	if !ok {
+		return nil, errors.Wrap(ErrUnknownProfile, name)
+	}
+	if contains(chain, name) {
+		return nil, errors.Errorf("profile inheritance cycle: %s", strings.Join(append(chain, name), " -> "))
+	}
+	resolved := *profile
+	resolved.Args = append([]string(nil), profile.Args...)
+	resolved.Config = NewConfig()
+	if profile.Config != nil {
+		*resolved.Config = *profile.Config
+	}
+	if profile.Extends == "" {
+		return &resolved, nil
+	}
+	parent, err := l.resolve(profile.Extends, append(chain, name))
+	if err != nil {
+		return nil, err
+	}
+	resolved.Extends = ""
+	resolved.Remove = nil
+	resolved.Args = mergeSpec(ParseArgs(parent.Args), ParseArgs(profile.Args), profile.Remove).Args()
+	if resolved.BinPath == "" {
+		resolved.BinPath = parent.BinPath
+	}
+	if resolved.GracePeriod == "" {
+		resolved.GracePeriod = parent.GracePeriod
+	}
+	//文件中的config只覆盖指定的字段，代码中创建的config整体覆盖
+	cfg := NewConfig()
+	if parent.Config != nil {
+		*cfg = *parent.Config
+	}
+	if profile.configRaw != nil {
+		if err := json.Unmarshal(profile.configRaw, cfg); err != nil {
+			return nil, errors.Wrapf(err, "profile %q config", name)
+		}
+	} else if profile.Config != nil {
+		*cfg = *profile.Config
+	}
+	resolved.Config = cfg
+	return &resolved, nil
+}
+
+// mergeSpec 在base的基础上删除remove中的参数，再覆盖override中的参数
+func mergeSpec(base, override *ScanSpec, remove []string) *ScanSpec {
+	for _, name := range remove {
+		base.Remove(name)
+	}
+	return base.merge(override)
+}
@@ -0,0 +1,111 @@
+package nmap
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestProfileLibrary(t *testing.T) {
+	library, err := NewProfileLibrary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range library.Names() {
+		profile, err := library.Get(name)
+		if err != nil {
+			t.Fatalf("builtin profile %s: %v", name, err)
+		}
+		if profile.Extends != "" || profile.Config == nil {
+			t.Fatalf("builtin profile %s is not resolved: %+v", name, profile)
+		}
+	}
+
+	//继承quick的-T4，删除-F
+	scanner, err := library.Nmap("quick-web", "scanme.nmap.org")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"-p", "80,443,8000,8008,8080,8443,8888", "-sV", "--script", "http-title,ssl-cert", "-T", "4", "--open", "scanme.nmap.org"}
+	if !reflect.DeepEqual(scanner.Args, want) {
+		t.Fatalf("expected %v, but got %v", want, scanner.Args)
+	}
+
+	//config只覆盖指定的字段，-sS等参数继承service，--max-retries等参数添加
+	profile, err := library.Get("external-weekly")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := NewConfig()
+	cfg.ResultName = "external-weekly"
+	if !reflect.DeepEqual(profile.Config, cfg) {
+		t.Fatalf("expected config %+v, but got %+v", cfg, profile.Config)
+	}
+	spec := ParseArgs(profile.Args)
+	for _, name := range []string{"-sS", "-sV", "-O", "-Pn", "--top-ports", "--host-timeout"} {
+		if !spec.Has(name) {
+			t.Fatalf("expected %s in %v", name, profile.Args)
+		}
+	}
+
+	library.Add(&Profile{Name: "a", Extends: "b"}, &Profile{Name: "b", Extends: "a"}, &Profile{Name: "c", Extends: "missing"})
+	if _, err := library.Get("a"); err == nil {
+		t.Fatal("expected inheritance cycle error")
+	}
+	if _, err := library.Get("c"); !errors.Is(err, ErrUnknownProfile) {
+		t.Fatalf("expected ErrUnknownProfile, but got %v", err)
+	}
+}
+
+func TestSaveProfiles(t *testing.T) {
+	base := NewProfile("base", NewNmap().AddsS().AddT(4).Addtopports(100))
+	base.Version = 2
+	child := &Profile{Name: "child", Extends: "base", Remove: []string{"--top-ports"}, Args: []string{"-T", "3", "-p", "22,80"}}
+	for _, file := range []string{"profiles.yaml", "profiles.json"} {
+		path := filepath.Join(t.TempDir(), file)
+		if err := SaveProfiles(path, base, child); err != nil {
+			t.Fatal(err)
+		}
+		//config的key均为snake_case
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, key := range []string{"result_name", "show_hosthint", "show_host_port", "merge_row", "add_table", "save_xml_raw"} {
+			if !strings.Contains(string(data), key) {
+				t.Fatalf("%s: expected config key %s, but got\n%s", file, key, data)
+			}
+		}
+		library := &ProfileLibrary{profiles: make(map[string]*Profile)}
+		if err := library.LoadFile(path); err != nil {
+			t.Fatal(err)
+		}
+		if names := library.Names(); !reflect.DeepEqual(names, []string{"base", "child"}) {
+			t.Fatalf("%s: expected base and child, but got %v", file, names)
+		}
+		loaded, err := library.Get("base")
+		if err != nil {
+			t.Fatal(err)
+		}
+		//"100"在YAML中仍为字符串
+		if !reflect.DeepEqual(loaded.Args, base.Args) || loaded.Version != 2 || !reflect.DeepEqual(loaded.Config, base.Config) {
+			t.Fatalf("%s: expected %+v, but got %+v", file, base, loaded)
+		}
+		scanner, err := library.Nmap("child", "10.0.0.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"-sS", "-p", "22,80", "-T", "3", "10.0.0.1"}
+		if !reflect.DeepEqual(scanner.Args, want) {
+			t.Fatalf("%s: expected %v, but got %v", file, want, scanner.Args)
+		}
+	}
+
+	if _, err := ParseProfiles([]byte("args: [-sS]")); err == nil {
+		t.Fatal("expected error for profile without name")
+	}
+}
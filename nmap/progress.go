@@ -0,0 +1,142 @@
+package nmap
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// defaultStatsEvery 订阅进度但未指定--stats-every时使用的间隔
+const defaultStatsEvery = "5s"
+
+// Progress 扫描进度，由taskbegin/taskprogress/taskend/host/runstats元素实时计算
+type Progress struct {
+	//当前任务，如 SYN Stealth Scan
+	Task string `json:"task"`
+	//当前任务的完成百分比
+	Percent float32 `json:"percent"`
+	//当前任务剩余时间
+	Remaining time.Duration `json:"remaining"`
+	//当前任务预计完成时间
+	ETA time.Time `json:"eta"`
+	//进度更新时间
+	Time time.Time `json:"time"`
+	//扫描开始时间
+	Start time.Time `json:"start"`
+	//已完成的host数
+	HostsCompleted int `json:"hostscompleted"`
+	//host总数，来自RunStats.Hosts，扫描结束前为0
+	HostsTotal int `json:"hoststotal"`
+	HostsUp    int `json:"hostsup"`
+	HostsDown  int `json:"hostsdown"`
+	//扫描是否结束
+	Done bool `json:"done"`
+}
+
+// ProgressFunc 进度回调，返回error时终止扫描，该error作为运行错误返回
+type ProgressFunc func(progress Progress) error
+
+// OnProgress 订阅扫描进度，可多次调用添加多个回调
+//
+// 进度依赖nmap的taskprogress输出，未通过Addstatsevery指定时，运行时自动添加 --stats-every 5s
+func (receiver *nmap) OnProgress(fn ProgressFunc) *nmap {
+	receiver.progressFuncs = append(receiver.progressFuncs, fn)
+	return receiver
+}
+
+// ETABudget 预计完成时间超过扫描开始后budget时终止扫描
+func ETABudget(budget time.Duration) ProgressFunc {
+	return func(progress Progress) error {
+		if progress.ETA.IsZero() {
+			return nil
+		}
+		if eta := progress.ETA.Sub(progress.Start); eta > budget {
+			return errors.Errorf("%s eta %s exceeds budget %s", progress.Task, eta.Round(time.Second), budget)
+		}
+		return nil
+	}
+}
+
+// progressTracker 根据流式事件维护当前进度并通知订阅者
+type progressTracker struct {
+	progress Progress
+	funcs    []ProgressFunc
+}
+
+func newProgressTracker(funcs []ProgressFunc) *progressTracker {
+	return &progressTracker{
+		progress: Progress{Start: time.Now()},
+		funcs:    funcs,
+	}
+}
+
+func (t *progressTracker) update(event *StreamEvent) error {
+	p := &t.progress
+	switch event.Type {
+	case EventTaskBegin:
+		p.Task = event.TaskBegin.Task
+		p.Percent = 0
+		p.Remaining = 0
+		p.ETA = time.Time{}
+		p.Time = time.Unix(event.TaskBegin.Time, 0)
+	case EventTaskProgress:
+		p.Task = event.TaskProgress.Task
+		p.Percent = event.TaskProgress.Percent
+		p.Remaining = time.Duration(event.TaskProgress.Remaining) * time.Second
+		p.ETA = time.Unix(event.TaskProgress.Etc, 0)
+		p.Time = time.Unix(event.TaskProgress.Time, 0)
+	case EventTaskEnd:
+		p.Task = event.TaskEnd.Task
+		p.Percent = 100
+		p.Remaining = 0
+		p.ETA = time.Unix(event.TaskEnd.Time, 0)
+		p.Time = time.Unix(event.TaskEnd.Time, 0)
+	case EventHost:
+		p.HostsCompleted++
+		switch event.Host.Status.State {
+		case HostStateUp:
+			p.HostsUp++
+		case HostStateDown:
+			p.HostsDown++
+		}
+		p.Time = time.Now()
+	case EventRunStats:
+		hosts := event.RunStats.Hosts
+		p.HostsTotal = hosts.Total
+		p.HostsUp = hosts.Up
+		p.HostsDown = hosts.Down
+		p.Remaining = 0
+		p.Done = true
+		p.Time = time.Unix(event.RunStats.Finished.Time, 0)
+	default:
+		return nil
+	}
+	for _, fn := range t.funcs {
+		if err := fn(t.progress); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hasArg 参数中是否已包含指定选项，包括--stats-every=10s、-T4等带值的写法，以及-sSV等合并的参数和别名
+func hasArg(args []string, arg string) bool {
+	expanded, _ := expandArgs(args)
+	options, _ := parseArgs(expanded)
+	for _, option := range options {
+		if option.Name == arg {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAnyArg 参数中是否包含其中一个选项
+func hasAnyArg(args []string, options ...string) bool {
+	for _, option := range options {
+		if hasArg(args, option) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,369 @@
+package nmap
+
+import (
+	"strings"
+)
+
+// HostSet 可链式过滤的host集合，元素指向NmapXMLResult中的Host
+//
+//	result.Hosts().Up().WithOpenPort(443).WithService("http*").WithScript("ssl-cert")
+type HostSet []*Host
+
+// HostPort 开放端口及其所在的host
+type HostPort struct {
+	Host *Host
+	Port *Port
+}
+
+// Hosts 结果中所有的host
+func (r *NmapXMLResult) Hosts() HostSet {
+	hosts := make(HostSet, 0, len(r.Host))
+	for i := range r.Host {
+		hosts = append(hosts, &r.Host[i])
+	}
+	return hosts
+}
+
+// OpenPorts 结果中所有开放的端口
+func (r *NmapXMLResult) OpenPorts() []HostPort {
+	return r.Hosts().OpenPorts()
+}
+
+// Filter 保留满足条件的host
+func (s HostSet) Filter(fn func(host *Host) bool) HostSet {
+	hosts := make(HostSet, 0, len(s))
+	for _, host := range s {
+		if fn(host) {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// Up 存活的host
+func (s HostSet) Up() HostSet {
+	return s.Filter(func(host *Host) bool {
+		return host.Status.State == HostStateUp
+	})
+}
+
+// Down 不存活的host
+func (s HostSet) Down() HostSet {
+	return s.Filter(func(host *Host) bool {
+		return host.Status.State == HostStateDown
+	})
+}
+
+// WithOpenPort 开放了任一端口的host
+func (s HostSet) WithOpenPort(ports ...uint16) HostSet {
+	return s.Filter(func(host *Host) bool {
+		for _, port := range host.OpenPorts() {
+			for _, id := range ports {
+				if port.PortId == id {
+					return true
+				}
+			}
+		}
+		return false
+	})
+}
+
+// WithService 开放端口的服务名匹配pattern的host，pattern支持*和?通配符，如 http*
+func (s HostSet) WithService(pattern string) HostSet {
+	return s.Filter(func(host *Host) bool {
+		for _, port := range host.OpenPorts() {
+			if match(pattern, port.Service.Name) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// WithScript 主机脚本或端口脚本中包含指定脚本的host
+func (s HostSet) WithScript(id string) HostSet {
+	return s.WithScriptOutput(id, "")
+}
+
+// WithScriptOutput 指定脚本的输出包含substr的host
+func (s HostSet) WithScriptOutput(id, substr string) HostSet {
+	return s.Filter(func(host *Host) bool {
+		for _, script := range host.Scripts() {
+			if script.Id == id && strings.Contains(script.Output, substr) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// WithOSAccuracy 操作系统识别准确率不低于accuracy的host
+func (s HostSet) WithOSAccuracy(accuracy int) HostSet {
+	return s.Filter(func(host *Host) bool {
+		for _, os := range host.OS {
+			for _, osMatch := range os.OSMatch {
+				if osMatch.Accuracy >= accuracy {
+					return true
+				}
+			}
+		}
+		return false
+	})
+}
+
+// WithOS 操作系统识别结果名称匹配pattern的host，如 *Windows*
+func (s HostSet) WithOS(pattern string) HostSet {
+	return s.Filter(func(host *Host) bool {
+		for _, os := range host.OS {
+			for _, osMatch := range os.OSMatch {
+				if match(pattern, osMatch.Name) {
+					return true
+				}
+			}
+		}
+		return false
+	})
+}
+
+// WithCPE 开放端口服务或操作系统的cpe匹配pattern的host，如 cpe:/a:apache:*
+func (s HostSet) WithCPE(pattern string) HostSet {
+	return s.Filter(func(host *Host) bool {
+		for _, cpe := range host.CPE() {
+			if match(pattern, cpe) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// OpenPorts 所有host开放的端口
+func (s HostSet) OpenPorts() []HostPort {
+	var hostPorts []HostPort
+	for _, host := range s {
+		for _, port := range host.OpenPorts() {
+			hostPorts = append(hostPorts, HostPort{Host: host, Port: port})
+		}
+	}
+	return hostPorts
+}
+
+// Addrs 所有host的ip地址
+func (s HostSet) Addrs() []string {
+	addrs := make([]string, 0, len(s))
+	for _, host := range s {
+		if ip := host.IP(); ip != "" {
+			addrs = append(addrs, ip)
+		}
+	}
+	return addrs
+}
+
+// ByIP 按ip地址查找host，未找到时为nil
+func (s HostSet) ByIP(ip string) *Host {
+	return s.byAddr(ip, func(addr Address) bool {
+		return addr.AddrType != "mac"
+	})
+}
+
+// ByMAC 按mac地址查找host，忽略大小写
+func (s HostSet) ByMAC(mac string) *Host {
+	return s.byAddr(mac, func(addr Address) bool {
+		return addr.AddrType == "mac"
+	})
+}
+
+// ByHostname 按主机名查找host，忽略大小写
+func (s HostSet) ByHostname(name string) *Host {
+	for _, host := range s {
+		for _, hostname := range host.Hostnames {
+			if strings.EqualFold(hostname.Name, name) {
+				return host
+			}
+		}
+	}
+	return nil
+}
+
+func (s HostSet) byAddr(value string, fn func(addr Address) bool) *Host {
+	for _, host := range s {
+		for _, addr := range host.Address {
+			if fn(addr) && strings.EqualFold(addr.Addr, value) {
+				return host
+			}
+		}
+	}
+	return nil
+}
+
+// IP host的ip地址（ipv4或ipv6）
+func (h *Host) IP() string {
+	for _, addr := range h.Address {
+		if addr.AddrType != "mac" {
+			return addr.Addr
+		}
+	}
+	return ""
+}
+
+// MAC host的mac地址，仅局域网扫描时存在
+func (h *Host) MAC() string {
+	for _, addr := range h.Address {
+		if addr.AddrType == "mac" {
+			return addr.Addr
+		}
+	}
+	return ""
+}
+
+// Addrs host的所有地址
+func (h *Host) Addrs() []string {
+	return addrList(h.Address)
+}
+
+// Names host的所有主机名
+func (h *Host) Names() []string {
+	names := make([]string, 0, len(h.Hostnames))
+	for _, hostname := range h.Hostnames {
+		names = append(names, hostname.Name)
+	}
+	return names
+}
+
+// PortList host的所有端口
+func (h *Host) PortList() []*Port {
+	var ports []*Port
+	for i := range h.Ports {
+		for j := range h.Ports[i].Port {
+			ports = append(ports, &h.Ports[i].Port[j])
+		}
+	}
+	return ports
+}
+
+// OpenPorts host开放的端口
+func (h *Host) OpenPorts() []*Port {
+	var ports []*Port
+	for _, port := range h.PortList() {
+		if port.IsOpen() {
+			ports = append(ports, port)
+		}
+	}
+	return ports
+}
+
+// FindPort 按协议和端口号查找端口，未找到时为nil
+func (h *Host) FindPort(protocol PortProtocol, id uint16) *Port {
+	for _, port := range h.PortList() {
+		if port.Protocol == protocol && port.PortId == id {
+			return port
+		}
+	}
+	return nil
+}
+
+// Scripts host的所有脚本结果，主机脚本在前端口脚本在后
+func (h *Host) Scripts() []*Script {
+	var scripts []*Script
+	for i := range h.HostScript {
+		scripts = append(scripts, &h.HostScript[i])
+	}
+	for _, port := range h.PortList() {
+		for i := range port.Script {
+			scripts = append(scripts, &port.Script[i])
+		}
+	}
+	return scripts
+}
+
+// FindScript 查找主机脚本结果，未找到时为nil
+func (h *Host) FindScript(id string) *Script {
+	for i := range h.HostScript {
+		if h.HostScript[i].Id == id {
+			return &h.HostScript[i]
+		}
+	}
+	return nil
+}
+
+// CPE host开放端口服务和操作系统识别结果的cpe，已去重
+func (h *Host) CPE() []string {
+	var cpes []string
+	seen := make(map[string]bool)
+	add := func(cpe string) {
+		if !seen[cpe] {
+			seen[cpe] = true
+			cpes = append(cpes, cpe)
+		}
+	}
+	for _, port := range h.OpenPorts() {
+		for _, cpe := range port.Service.CPE {
+			add(cpe)
+		}
+	}
+	for _, os := range h.OS {
+		for _, osMatch := range os.OSMatch {
+			for _, osClass := range osMatch.OSClass {
+				for _, cpe := range osClass.CPE {
+					add(cpe)
+				}
+			}
+		}
+	}
+	return cpes
+}
+
+// bestOSMatch 准确率最高的操作系统识别结果，没有时为nil
+func bestOSMatch(host *Host) *OSMatch {
+	var best *OSMatch
+	for i := range host.OS {
+		for j := range host.OS[i].OSMatch {
+			if osMatch := &host.OS[i].OSMatch[j]; best == nil || osMatch.Accuracy > best.Accuracy {
+				best = osMatch
+			}
+		}
+	}
+	return best
+}
+
+// IsOpen 端口是否开放
+func (p *Port) IsOpen() bool {
+	return p.State.State == PortState(Open)
+}
+
+// FindScript 查找端口脚本结果，未找到时为nil
+func (p *Port) FindScript(id string) *Script {
+	for i := range p.Script {
+		if p.Script[i].Id == id {
+			return &p.Script[i]
+		}
+	}
+	return nil
+}
+
+// match 通配符匹配，忽略大小写，*匹配任意字符（包括/），?匹配单个字符
+func match(pattern, name string) bool {
+	pattern, name = strings.ToLower(pattern), strings.ToLower(name)
+	//上一个*的位置，以及该*匹配到name的位置
+	star, next := -1, 0
+	p, n := 0, 0
+	for n < len(name) {
+		switch {
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == name[n]):
+			p++
+			n++
+		case p < len(pattern) && pattern[p] == '*':
+			star, next = p, n
+			p++
+		case star >= 0:
+			next++
+			p, n = star+1, next
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
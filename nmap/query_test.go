@@ -0,0 +1,63 @@
+package nmap
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHostSet(t *testing.T) {
+	result := loadXML(t, "testdata/scanme.xml")
+	hosts := result.Hosts()
+	if len(hosts) != 2 || len(hosts.Up()) != 2 || len(hosts.Down()) != 0 {
+		t.Fatalf("unexpected hosts %d", len(hosts))
+	}
+	for name, expected := range map[string]struct {
+		hosts HostSet
+		addrs []string
+	}{
+		"chain":        {hosts.Up().WithOpenPort(443).WithService("http*").WithScript("ssl-cert"), []string{"45.33.32.156"}},
+		"port":         {hosts.WithOpenPort(445, 22), []string{"45.33.32.156", "192.168.1.10"}},
+		"open port":    {hosts.WithOpenPort(31337, 3389), []string{}},
+		"service":      {hosts.WithService("microsoft-?s"), []string{"192.168.1.10"}},
+		"host script":  {hosts.WithScript("smb-os-discovery"), []string{"192.168.1.10"}},
+		"output":       {hosts.WithScriptOutput("http-title", "ScanMe"), []string{"45.33.32.156"}},
+		"os accuracy":  {hosts.WithOSAccuracy(96), []string{"192.168.1.10"}},
+		"os":           {hosts.WithOS("*windows*"), []string{"192.168.1.10"}},
+		"cpe":          {hosts.WithCPE("cpe:/a:apache:*"), []string{"45.33.32.156"}},
+		"os cpe":       {hosts.WithCPE("*linux_kernel:5"), []string{"45.33.32.156"}},
+		"no such cpe":  {hosts.WithCPE("cpe:/a:microsoft:iis*"), []string{}},
+		"empty filter": {hosts.WithOpenPort(), []string{}},
+	} {
+		if addrs := expected.hosts.Addrs(); !reflect.DeepEqual(addrs, expected.addrs) {
+			t.Errorf("%s: expected %v, but got %v", name, expected.addrs, addrs)
+		}
+	}
+
+	openPorts := result.OpenPorts()
+	if len(openPorts) != 7 || openPorts[0].Host.IP() != "45.33.32.156" || openPorts[6].Port.PortId != 445 {
+		t.Errorf("unexpected open ports %v", openPorts)
+	}
+	if host := hosts.ByIP("192.168.1.10"); host == nil || host.MAC() != "00:0C:29:3E:5A:11" {
+		t.Errorf("expected host by ip")
+	}
+	if host := hosts.ByMAC("00:0c:29:3e:5a:11"); host == nil || host.IP() != "192.168.1.10" {
+		t.Errorf("expected host by mac")
+	}
+	if host := hosts.ByHostname("SCANME.nmap.org"); host != &result.Host[0] {
+		t.Errorf("expected host by hostname")
+	}
+	if hosts.ByIP("00:0C:29:3E:5A:11") != nil || hosts.ByIP("10.0.0.1") != nil {
+		t.Errorf("expected no host")
+	}
+
+	host := hosts[0]
+	if port := host.FindPort(PortProtocolTcp, 443); port == nil || port.FindScript("ssl-enum-ciphers") == nil || port.FindScript("banner") != nil {
+		t.Errorf("unexpected port 443 %v", port)
+	}
+	if host.FindScript("dns-nsid") == nil || len(host.Scripts()) != 8 || len(host.PortList()) != 5 {
+		t.Errorf("unexpected scripts %d ports %d", len(host.Scripts()), len(host.PortList()))
+	}
+	if !reflect.DeepEqual(host.Names(), []string{"scanme.nmap.org", "scanme.nmap.org"}) {
+		t.Errorf("unexpected names %v", host.Names())
+	}
+}
@@ -0,0 +1,125 @@
+package nmap
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Recovery 容错解析的结果，说明xml在哪里被截断
+type Recovery struct {
+	//xml是否完整，包含</nmaprun>和runstats
+	Complete bool `json:"complete"`
+	//最后一个完整元素结束的位置（字节偏移）
+	Offset int64 `json:"offset"`
+	//最后一个完整元素结束的位置所在的行
+	Line int `json:"line"`
+	//截断时正在解析的顶层元素，如host，为空表示截断在元素之间
+	Element string `json:"element"`
+	//解析中止的原因，xml完整时为nil
+	Err error `json:"-"`
+}
+
+func (r *Recovery) String() string {
+	if r.Complete {
+		return "complete"
+	}
+	cut := fmt.Sprintf("cut at line %d (offset %d)", r.Line, r.Offset)
+	if r.Element != "" {
+		cut += " inside <" + r.Element + ">"
+	}
+	if r.Err != nil {
+		cut += ": " + r.Err.Error()
+	}
+	return cut
+}
+
+// RecoverXML 容错解析xml结果，用于被终止的扫描、磁盘写满或--resume产生的缺少</nmaprun>和runstats的xml
+//
+// 保留所有完整的host等元素，缺少runstats时根据已解析的host生成RunStats（Incomplete为true），
+// 只有找不到nmaprun元素时返回error
+func RecoverXML(data []byte) (*NmapXMLResult, *Recovery, error) {
+	result := &NmapXMLResult{}
+	decoder := newStreamDecoder(bytes.NewReader(data), result, nil)
+	err := decoder.decode()
+	if !decoder.root {
+		if err == nil {
+			err = errors.New("no nmaprun element found in xml output")
+		}
+		return nil, nil, &XMLParseError{Err: err}
+	}
+	recovery := decoder.recovery(data, err)
+	if !decoder.runStats {
+		synthesizeRunStats(result, recovery)
+	}
+	return result, recovery, nil
+}
+
+// RecoverXMLFile 容错解析xml文件，如SaveXmlRaw保存的xml结果
+func RecoverXMLFile(fileName string) (*NmapXMLResult, *Recovery, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, nil, err
+	}
+	return RecoverXML(data)
+}
+
+// recovery 根据解析状态生成Recovery，data为已读取的xml，用于计算截断的行
+func (d *streamDecoder) recovery(data []byte, err error) *Recovery {
+	recovery := &Recovery{
+		Complete: err == nil && d.closed && d.runStats,
+		Offset:   d.offset,
+		Element:  d.element,
+		Err:      err,
+	}
+	if recovery.Offset <= int64(len(data)) {
+		recovery.Line = bytes.Count(data[:recovery.Offset], []byte("\n")) + 1
+	}
+	return recovery
+}
+
+// synthesizeRunStats 根据已解析的host生成RunStats
+func synthesizeRunStats(result *NmapXMLResult, recovery *Recovery) {
+	var up, down int
+	finished := result.Start
+	for _, host := range result.Host {
+		switch host.Status.State {
+		case HostStateUp:
+			up++
+		case HostStateDown:
+			down++
+		}
+		if host.EndTime > finished {
+			finished = host.EndTime
+		}
+	}
+	for _, taskEnd := range result.TaskEnd {
+		if taskEnd.Time > finished {
+			finished = taskEnd.Time
+		}
+	}
+	runStats := RunStats{
+		Finished: Finished{
+			Time:     finished,
+			Summary:  fmt.Sprintf("Nmap output incomplete; %d IP addresses (%d hosts up) recovered", up+down, up),
+			Exit:     "error",
+			ErrorMsg: "xml output " + recovery.String(),
+		},
+		Hosts: Hosts{
+			Up:    up,
+			Down:  down,
+			Total: up + down,
+		},
+		Incomplete: true,
+	}
+	if finished != 0 {
+		runStats.Finished.TimeStr = time.Unix(finished, 0).Format(time.ANSIC)
+	}
+	if result.Start != 0 {
+		runStats.Finished.Elapsed = float32(finished - result.Start)
+	}
+	result.RunStats = runStats
+}
@@ -0,0 +1,171 @@
+package nmap
+
+import (
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// 结果文件的格式
+const (
+	OutputNormal   = "normal"
+	OutputXML      = "xml"
+	OutputGrepable = "grepable"
+	OutputKiddie   = "kiddie"
+)
+
+// outputFlags 输出参数对应的格式，-oM为-oG的旧名称
+var outputFlags = map[string]string{
+	"-oN": OutputNormal,
+	"-oX": OutputXML,
+	"-oG": OutputGrepable,
+	"-oM": OutputGrepable,
+	"-oS": OutputKiddie,
+}
+
+// OutputFile nmap运行后写入的结果文件
+type OutputFile struct {
+	Format string `json:"format"`
+	Path   string `json:"path"`
+}
+
+// outputPlan 处理后的输出参数，始终保留一个xml通道用于解析
+//
+// 标准输出未被其他格式（如-oN -）占用时，xml输出到标准输出，边读取边解析；否则xml由nmap写入文件，边读取文件边解析
+type outputPlan struct {
+	args []string
+	//返回给调用者的结果文件
+	files []OutputFile
+	//是否有xml通道，--script-help等不输出xml
+	xml bool
+	//nmap写入xml的文件，为空时为标准输出
+	xmlPath string
+	//xmlPath为临时文件，运行结束后删除
+	temp bool
+	//由xml通道的内容写入的xml文件，包括-oX <file>和-oA的.xml
+	xmlFiles []string
+	//--append-output，追加到已存在的文件
+	appendOutput bool
+}
+
+// planOutput 处理-oN、-oX、-oG、-oS、-oA参数（包括-oX=file的写法），-oA拆分为-oN、-oG和xml文件，-oX <file>由xml通道写入
+func planOutput(args []string, noXML bool) (*outputPlan, error) {
+	plan := &outputPlan{xml: !noXML, appendOutput: hasArg(args, "--append-output")}
+	stdoutFormat := ""
+	for i := 0; i < len(args); i++ {
+		//-oX scan.xml或-oX=scan.xml
+		arg, name, attached := strings.Cut(args[i], "=")
+		format, ok := outputFlags[arg]
+		if arg != "-oA" && !ok || !attached && i+1 == len(args) || noXML {
+			plan.args = append(plan.args, args[i])
+			continue
+		}
+		if !attached {
+			i++
+			name = args[i]
+		}
+		switch {
+		case arg == "-oA":
+			plan.args = append(plan.args, "-oN", name+".nmap", "-oG", name+".gnmap")
+			plan.xmlFiles = append(plan.xmlFiles, name+".xml")
+			plan.files = append(plan.files,
+				OutputFile{Format: OutputNormal, Path: name + ".nmap"},
+				OutputFile{Format: OutputXML, Path: name + ".xml"},
+				OutputFile{Format: OutputGrepable, Path: name + ".gnmap"})
+		case name == "-":
+			stdoutFormat = format
+			if format != OutputXML {
+				plan.args = append(plan.args, arg, name)
+			}
+		case format == OutputXML:
+			plan.xmlFiles = append(plan.xmlFiles, name)
+			plan.files = append(plan.files, OutputFile{Format: format, Path: name})
+		default:
+			plan.args = append(plan.args, arg, name)
+			plan.files = append(plan.files, OutputFile{Format: format, Path: name})
+		}
+	}
+	if !plan.xml {
+		return plan, nil
+	}
+	if stdoutFormat == "" || stdoutFormat == OutputXML {
+		plan.args = append(plan.args, "-oX", "-")
+		return plan, nil
+	}
+	//标准输出被占用，xml写入第一个xml文件或临时文件
+	if len(plan.xmlFiles) != 0 {
+		plan.xmlPath = plan.xmlFiles[0]
+		plan.xmlFiles = plan.xmlFiles[1:]
+	} else {
+		file, err := os.CreateTemp("", "nmap-go-*.xml")
+		if err != nil {
+			return nil, err
+		}
+		file.Close()
+		plan.xmlPath = file.Name()
+		plan.temp = true
+	}
+	plan.args = append(plan.args, "-oX", plan.xmlPath)
+	return plan, nil
+}
+
+// writeXMLFiles 将xml通道的内容写入xml文件
+func (plan *outputPlan) writeXMLFiles(raw []byte) error {
+	flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if plan.appendOutput {
+		flag = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	for _, name := range plan.xmlFiles {
+		file, err := os.OpenFile(name, flag, 0644)
+		if err != nil {
+			return err
+		}
+		_, err = file.Write(raw)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// xmlFollowInterval 读取到nmap正在写入的xml文件的结尾时，等待新内容的间隔
+const xmlFollowInterval = 100 * time.Millisecond
+
+// openXMLFile 在nmap启动前打开xml文件，--append-output时从已有的内容之后读取
+func (plan *outputPlan) openXMLFile() (*os.File, error) {
+	file, err := os.OpenFile(plan.xmlPath, os.O_RDONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if plan.appendOutput {
+		if _, err := file.Seek(0, io.SeekEnd); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	return file, nil
+}
+
+// followReader 读取nmap正在写入的文件，读到结尾时等待新内容，直到done关闭（nmap退出）后读完剩余的内容
+type followReader struct {
+	file *os.File
+	done <-chan struct{}
+}
+
+func (r *followReader) Read(p []byte) (int, error) {
+	for {
+		n, err := r.file.Read(p)
+		if n != 0 || err != io.EOF {
+			return n, err
+		}
+		select {
+		case <-r.done:
+			return r.file.Read(p)
+		case <-time.After(xmlFollowInterval):
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package nmap
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestPlanOutput(t *testing.T) {
+	cases := []struct {
+		name  string
+		args  []string
+		want  []string
+		files []OutputFile
+	}{
+		{"default", []string{"-sV"}, []string{"-sV", "-oX", "-"}, nil},
+		{"xml stdout", []string{"-oX", "-"}, []string{"-oX", "-"}, nil},
+		{"normal file", []string{"-oN", "a.nmap"}, []string{"-oN", "a.nmap", "-oX", "-"}, []OutputFile{{OutputNormal, "a.nmap"}}},
+		{"xml file", []string{"-oX", "a.xml", "-oG", "a.gnmap"}, []string{"-oG", "a.gnmap", "-oX", "-"},
+			[]OutputFile{{OutputXML, "a.xml"}, {OutputGrepable, "a.gnmap"}}},
+		{"all", []string{"-oA", "a"}, []string{"-oN", "a.nmap", "-oG", "a.gnmap", "-oX", "-"},
+			[]OutputFile{{OutputNormal, "a.nmap"}, {OutputXML, "a.xml"}, {OutputGrepable, "a.gnmap"}}},
+		{"normal stdout with xml file", []string{"-oN", "-", "-oX", "a.xml"}, []string{"-oN", "-", "-oX", "a.xml"}, []OutputFile{{OutputXML, "a.xml"}}},
+		{"attached", []string{"-oX=a.xml", "-oA=b", "-oN=-"}, []string{"-oN", "b.nmap", "-oG", "b.gnmap", "-oN", "-", "-oX", "a.xml"},
+			[]OutputFile{{OutputXML, "a.xml"}, {OutputNormal, "b.nmap"}, {OutputXML, "b.xml"}, {OutputGrepable, "b.gnmap"}}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			plan, err := planOutput(c.args, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(plan.args, c.want) || !reflect.DeepEqual(plan.files, c.files) {
+				t.Errorf("expected %v %v, but got %v %v", c.want, c.files, plan.args, plan.files)
+			}
+		})
+	}
+	plan, err := planOutput([]string{"-oG", "-"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(plan.xmlPath)
+	if !plan.temp || plan.args[len(plan.args)-1] != plan.xmlPath {
+		t.Errorf("expected xml in temp file, but got %v", plan.args)
+	}
+	if plan, _ := planOutput([]string{"--script-help", "http-title", "-oN", "a"}, true); plan.xml || len(plan.args) != 4 {
+		t.Errorf("expected no xml channel, but got %v", plan.args)
+	}
+}
+
+func TestRunOutputFiles(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake nmap is a shell script")
+	}
+	testdata, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	//按参数输出xml和normal结果
+	script := `#!/bin/sh
+xml=
+normal=
+while [ $# -gt 0 ]; do
+	case "$1" in
+	-oX) xml=$2; shift ;;
+	-oN) normal=$2; shift ;;
+	esac
+	shift
+done
+if [ "$normal" = - ]; then cat ` + testdata + `/scanme.nmap; elif [ -n "$normal" ]; then cp ` + testdata + `/scanme.nmap "$normal"; fi
+if [ "$xml" = - ]; then cat ` + testdata + `/scanme.xml; elif [ -n "$xml" ]; then cp ` + testdata + `/scanme.xml "$xml"; fi
+`
+	dir := t.TempDir()
+	binPath := filepath.Join(dir, "nmap")
+	if err := os.WriteFile(binPath, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	cfg := NewConfig()
+	cfg.SaveXmlRaw = false
+	base := filepath.Join(dir, "scan")
+
+	n := NewNmap(cfg).AddoA(base)
+	n.BinPath = binPath
+	result, err := n.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.XML == nil || len(result.XML.OpenPorts()) != 7 || len(result.Files) != 3 {
+		t.Fatalf("expected parsed xml and 3 files, but got %+v", result.Files)
+	}
+	for _, file := range result.Files {
+		if file.Format == OutputGrepable {
+			continue
+		}
+		if _, err := os.Stat(file.Path); err != nil {
+			t.Errorf("%s file not written: %v", file.Format, err)
+		}
+	}
+
+	//标准输出为normal结果，xml通过临时文件解析
+	n = NewNmap(cfg).AddoN()
+	n.BinPath = binPath
+	result, err = n.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.XML == nil || len(result.XML.OpenPorts()) != 7 || !strings.HasPrefix(result.Raw, "# Nmap 7.92") {
+		t.Errorf("expected parsed xml and normal stdout")
+	}
+}
+
+func TestRunStreamXMLFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake nmap is a shell script")
+	}
+	testdata, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	//先写入第一个host，等到收到该host后再写入剩余的结果，5秒内没有收到时写入late
+	script := `#!/bin/sh
+while [ $# -gt 0 ]; do
+	[ "$1" = -oX ] && xml=$2
+	shift
+done
+cat ` + testdata + `/scanme.nmap
+sed -n '1,/<\/host>/p' ` + testdata + `/scanme.xml > "$xml"
+i=0
+while [ ! -f ` + dir + `/seen ] && [ $i -lt 50 ]; do
+	sleep 0.1
+	i=$((i+1))
+done
+[ -f ` + dir + `/seen ] || touch ` + dir + `/late
+sed '1,/<\/host>/d' ` + testdata + `/scanme.xml >> "$xml"
+`
+	binPath := filepath.Join(dir, "nmap")
+	if err := os.WriteFile(binPath, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	cfg := NewConfig()
+	cfg.SaveXmlRaw = false
+	n := NewNmap(cfg).AddoN()
+	n.BinPath = binPath
+	var hosts int
+	result, err := n.RunStream(func(event *StreamEvent) {
+		if event.Type != EventHost {
+			return
+		}
+		if hosts++; hosts == 1 {
+			if err := os.WriteFile(filepath.Join(dir, "seen"), nil, 0644); err != nil {
+				t.Error(err)
+			}
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "late")); err == nil {
+		t.Errorf("expected the first host while nmap is running")
+	}
+	if hosts < 2 || hosts != len(result.XML.Host) {
+		t.Errorf("expected all %d hosts, but got %d", len(result.XML.Host), hosts)
+	}
+}
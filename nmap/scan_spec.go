@@ -0,0 +1,645 @@
+package nmap
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ScanSpec 结构化的扫描参数，Add*方法添加的参数均对应其中的字段，通过Spec从Args解析得到
+//
+//	spec := scanner.Spec()
+//	spec.Ports                        //使用的端口
+//	spec.Timing.Template              //-T的值
+//	spec.Discovery.TCPSyn.Ports       //-PS的端口
+//	spec.Set("-T", "3")               //-T4替换为-T3
+//	spec.Remove("-sS").Set("-sT", "") //-sS替换为-sT
+//	scanner.SetSpec(spec)
+//
+// Args按固定的分类和字段顺序生成，结果与原参数顺序无关；只修改个别参数时使用SetOption、RemoveOption，其他参数保持原样
+type ScanSpec struct {
+	//扫描目标
+	Targets []string `json:"targets"`
+	//--exclude，多个--exclude合并
+	Excludes []string `json:"excludes"`
+	//-p，多个-p合并
+	Ports string `json:"ports"`
+	//扫描方式
+	Techniques TechniqueSpec `json:"techniques"`
+	//主机发现
+	Discovery DiscoverySpec `json:"discovery"`
+	//时间和性能
+	Timing TimingSpec `json:"timing"`
+	//防火墙/IDS躲避和欺骗
+	Evasion EvasionSpec `json:"evasion"`
+	//--script，按,分隔
+	Scripts []string `json:"scripts"`
+	//--script-args，按,分隔，如 user=foo、http.useragent="a,b"
+	ScriptArgs []string `json:"scriptArgs"`
+	//输出
+	Output OutputSpec `json:"output"`
+	//其他参数，包括端口、服务和操作系统识别、脚本的其他参数，以及未知的参数，
+	//未知的参数后紧跟不以-开头的参数时，无法确定是值还是目标，作为该参数的值保留
+	Options []Option `json:"options"`
+}
+
+// TechniqueSpec 扫描方式，字段的nmap tag为对应的参数
+type TechniqueSpec struct {
+	SYN            bool   `json:"syn,omitempty" nmap:"-sS"`
+	Connect        bool   `json:"connect,omitempty" nmap:"-sT"`
+	ACK            bool   `json:"ack,omitempty" nmap:"-sA"`
+	Window         bool   `json:"window,omitempty" nmap:"-sW"`
+	Maimon         bool   `json:"maimon,omitempty" nmap:"-sM"`
+	UDP            bool   `json:"udp,omitempty" nmap:"-sU"`
+	Null           bool   `json:"null,omitempty" nmap:"-sN"`
+	FIN            bool   `json:"fin,omitempty" nmap:"-sF"`
+	Xmas           bool   `json:"xmas,omitempty" nmap:"-sX"`
+	ScanFlags      string `json:"scanFlags,omitempty" nmap:"--scanflags"`
+	Idle           string `json:"idle,omitempty" nmap:"-sI"`
+	SCTPInit       bool   `json:"sctpInit,omitempty" nmap:"-sY"`
+	SCTPCookieEcho bool   `json:"sctpCookieEcho,omitempty" nmap:"-sZ"`
+	IPProtocol     bool   `json:"ipProtocol,omitempty" nmap:"-sO"`
+	FTPBounce      string `json:"ftpBounce,omitempty" nmap:"-b"`
+}
+
+// DiscoverySpec 主机发现
+type DiscoverySpec struct {
+	ListScan      bool  `json:"listScan,omitempty" nmap:"-sL"`
+	PingScan      bool  `json:"pingScan,omitempty" nmap:"-sn"`
+	SkipDiscovery bool  `json:"skipDiscovery,omitempty" nmap:"-Pn"`
+	TCPSyn        Probe `json:"tcpSyn" nmap:"-PS"`
+	TCPAck        Probe `json:"tcpAck" nmap:"-PA"`
+	UDP           Probe `json:"udp" nmap:"-PU"`
+	SCTP          Probe `json:"sctp" nmap:"-PY"`
+	ICMPEcho      bool  `json:"icmpEcho,omitempty" nmap:"-PE"`
+	ICMPTimestamp bool  `json:"icmpTimestamp,omitempty" nmap:"-PP"`
+	ICMPNetmask   bool  `json:"icmpNetmask,omitempty" nmap:"-PM"`
+	//值为协议列表
+	IPProtocol     Probe  `json:"ipProtocol" nmap:"-PO"`
+	ARP            bool   `json:"arp,omitempty" nmap:"-PR"`
+	DisableARPPing bool   `json:"disableArpPing,omitempty" nmap:"--disable-arp-ping"`
+	IgnoreRST      bool   `json:"ignoreRst,omitempty" nmap:"--discovery-ignore-rst"`
+	Traceroute     bool   `json:"traceroute,omitempty" nmap:"--traceroute"`
+	NoResolve      bool   `json:"noResolve,omitempty" nmap:"-n"`
+	AlwaysResolve  bool   `json:"alwaysResolve,omitempty" nmap:"-R"`
+	DNSServers     string `json:"dnsServers,omitempty" nmap:"--dns-servers"`
+	SystemDNS      bool   `json:"systemDns,omitempty" nmap:"--system-dns"`
+}
+
+// Probe 端口列表可以省略的主机发现探测，如-PS、-PS22,80
+type Probe struct {
+	Enabled bool `json:"enabled"`
+	//端口或协议列表，为空时使用nmap默认的端口
+	Ports string `json:"ports,omitempty"`
+}
+
+// TimingSpec 时间和性能，值为nmap参数的原始写法，如 4、aggressive、30m
+type TimingSpec struct {
+	Template            string `json:"template,omitempty" nmap:"-T"`
+	MinHostgroup        string `json:"minHostgroup,omitempty" nmap:"--min-hostgroup"`
+	MaxHostgroup        string `json:"maxHostgroup,omitempty" nmap:"--max-hostgroup"`
+	MinParallelism      string `json:"minParallelism,omitempty" nmap:"--min-parallelism"`
+	MaxParallelism      string `json:"maxParallelism,omitempty" nmap:"--max-parallelism"`
+	MinRTTTimeout       string `json:"minRttTimeout,omitempty" nmap:"--min-rtt-timeout"`
+	MaxRTTTimeout       string `json:"maxRttTimeout,omitempty" nmap:"--max-rtt-timeout"`
+	InitialRTTTimeout   string `json:"initialRttTimeout,omitempty" nmap:"--initial-rtt-timeout"`
+	MaxRetries          string `json:"maxRetries,omitempty" nmap:"--max-retries"`
+	HostTimeout         string `json:"hostTimeout,omitempty" nmap:"--host-timeout"`
+	ScanDelay           string `json:"scanDelay,omitempty" nmap:"--scan-delay"`
+	MaxScanDelay        string `json:"maxScanDelay,omitempty" nmap:"--max-scan-delay"`
+	MinRate             string `json:"minRate,omitempty" nmap:"--min-rate"`
+	MaxRate             string `json:"maxRate,omitempty" nmap:"--max-rate"`
+	DefeatRSTRatelimit  bool   `json:"defeatRstRatelimit,omitempty" nmap:"--defeat-rst-ratelimit"`
+	DefeatICMPRatelimit bool   `json:"defeatIcmpRatelimit,omitempty" nmap:"--defeat-icmp-ratelimit"`
+	NsockEngine         string `json:"nsockEngine,omitempty" nmap:"--nsock-engine"`
+}
+
+// EvasionSpec 防火墙/IDS躲避和欺骗
+type EvasionSpec struct {
+	//-f的次数，-ff为2
+	Fragment int    `json:"fragment,omitempty" nmap:"-f"`
+	MTU      string `json:"mtu,omitempty" nmap:"--mtu"`
+	Decoys   string `json:"decoys,omitempty" nmap:"-D"`
+	Source   string `json:"source,omitempty" nmap:"-S"`
+	//网卡
+	Interface string `json:"interface,omitempty" nmap:"-e"`
+	//-g或--source-port，生成-g
+	SourcePort     string `json:"sourcePort,omitempty" nmap:"-g,--source-port"`
+	Proxies        string `json:"proxies,omitempty" nmap:"--proxies"`
+	Data           string `json:"data,omitempty" nmap:"--data"`
+	DataString     string `json:"dataString,omitempty" nmap:"--data-string"`
+	DataLength     string `json:"dataLength,omitempty" nmap:"--data-length"`
+	IPOptions      string `json:"ipOptions,omitempty" nmap:"--ip-options"`
+	TTL            string `json:"ttl,omitempty" nmap:"--ttl"`
+	RandomizeHosts bool   `json:"randomizeHosts,omitempty" nmap:"--randomize-hosts"`
+	SpoofMAC       string `json:"spoofMac,omitempty" nmap:"--spoof-mac"`
+	BadSum         bool   `json:"badSum,omitempty" nmap:"--badsum"`
+	Adler32        bool   `json:"adler32,omitempty" nmap:"--adler32"`
+}
+
+// OutputSpec 输出
+type OutputSpec struct {
+	Normal       string `json:"normal,omitempty" nmap:"-oN"`
+	XML          string `json:"xml,omitempty" nmap:"-oX"`
+	ScriptKiddie string `json:"scriptKiddie,omitempty" nmap:"-oS"`
+	Grepable     string `json:"grepable,omitempty" nmap:"-oG"`
+	Machine      string `json:"machine,omitempty" nmap:"-oM"`
+	All          string `json:"all,omitempty" nmap:"-oA"`
+	//-v的级别，-vv、-v2为2
+	Verbosity int `json:"verbosity,omitempty" nmap:"-v"`
+	//-d的级别，-dd、-d2为2
+	Debug          int    `json:"debug,omitempty" nmap:"-d"`
+	Reason         bool   `json:"reason,omitempty" nmap:"--reason"`
+	Open           bool   `json:"open,omitempty" nmap:"--open"`
+	PacketTrace    bool   `json:"packetTrace,omitempty" nmap:"--packet-trace"`
+	Iflist         bool   `json:"iflist,omitempty" nmap:"--iflist"`
+	AppendOutput   bool   `json:"appendOutput,omitempty" nmap:"--append-output"`
+	Resume         string `json:"resume,omitempty" nmap:"--resume"`
+	Stylesheet     string `json:"stylesheet,omitempty" nmap:"--stylesheet"`
+	Webxml         bool   `json:"webxml,omitempty" nmap:"--webxml"`
+	NoStylesheet   bool   `json:"noStylesheet,omitempty" nmap:"--no-stylesheet"`
+	NonInteractive bool   `json:"nonInteractive,omitempty" nmap:"--noninteractive"`
+	StatsEvery     string `json:"statsEvery,omitempty" nmap:"--stats-every"`
+	LogErrors      bool   `json:"logErrors,omitempty" nmap:"--log-errors"`
+}
+
+// specField ScanSpec中nmap tag对应的字段
+type specField struct {
+	//tag中的参数，第一个用于生成参数
+	names []string
+	index []int
+}
+
+// specFields 参数对应的字段
+var specFields = func() map[string]specField {
+	fields := make(map[string]specField)
+	specType := reflect.TypeOf(ScanSpec{})
+	for i := 0; i < specType.NumField(); i++ {
+		group := specType.Field(i)
+		if group.Type.Kind() != reflect.Struct {
+			continue
+		}
+		for j := 0; j < group.Type.NumField(); j++ {
+			tag := group.Type.Field(j).Tag.Get("nmap")
+			if tag == "" {
+				continue
+			}
+			field := specField{names: strings.Split(tag, ","), index: []int{i, j}}
+			for _, name := range field.names {
+				fields[name] = field
+			}
+		}
+	}
+	return fields
+}()
+
+// ParseArgs 解析nmap参数为ScanSpec，合并的参数（如-sSV）和别名（如-P0）展开后解析
+func ParseArgs(args []string) *ScanSpec {
+	spec := &ScanSpec{}
+	for _, group := range groupArgs(args) {
+		if group.target {
+			spec.Targets = append(spec.Targets, group.tokens...)
+			continue
+		}
+		for _, option := range group.options {
+			spec.add(option)
+		}
+	}
+	return spec
+}
+
+// Spec 当前参数对应的ScanSpec，修改后通过SetSpec生效
+func (receiver *nmap) Spec() *ScanSpec {
+	return ParseArgs(receiver.Args)
+}
+
+// SetSpec 使用spec生成的参数替换当前参数
+func (receiver *nmap) SetSpec(spec *ScanSpec) *nmap {
+	receiver.Args = spec.Args()
+	return receiver
+}
+
+// SetOption 设置参数的值，如SetOption("-T", "3")，没有值的参数value为空；
+// 在原位置替换第一个同名参数并删除其他的，没有时添加到第一个目标之前，其他参数保持原样
+func (receiver *nmap) SetOption(name, value string) *nmap {
+	replacement := (&ScanSpec{}).Set(name, value).Args()
+	args, found := editArgs(receiver.Args, name, replacement)
+	if !found {
+		insert := len(receiver.Args)
+		for _, group := range groupArgs(receiver.Args) {
+			if group.target {
+				insert = group.start
+				break
+			}
+		}
+		args = append(append(append([]string(nil), receiver.Args[:insert]...), replacement...), receiver.Args[insert:]...)
+	}
+	receiver.Args = args
+	return receiver
+}
+
+// RemoveOption 删除参数，其他参数保持原样
+func (receiver *nmap) RemoveOption(name string) *nmap {
+	receiver.Args, _ = editArgs(receiver.Args, name, nil)
+	return receiver
+}
+
+// Args 生成nmap参数，顺序为扫描方式、主机发现、端口、其他参数、脚本、时间、躲避、输出、排除的目标、目标，
+// 同一分类中按字段的顺序
+func (s *ScanSpec) Args() []string {
+	var args []string
+	appendFields := func(group any) {
+		value := reflect.ValueOf(group).Elem()
+		for i := 0; i < value.NumField(); i++ {
+			if tag := value.Type().Field(i).Tag.Get("nmap"); tag != "" {
+				name, _, _ := strings.Cut(tag, ",")
+				args = append(args, fieldArgs(name, value.Field(i).Addr().Interface())...)
+			}
+		}
+	}
+	appendFields(&s.Techniques)
+	appendFields(&s.Discovery)
+	if s.Ports != "" {
+		args = append(args, "-p", s.Ports)
+	}
+	for _, option := range s.Options {
+		args = append(args, option.args()...)
+	}
+	if len(s.Scripts) != 0 {
+		args = append(args, "--script", strings.Join(s.Scripts, ","))
+	}
+	if len(s.ScriptArgs) != 0 {
+		args = append(args, "--script-args", strings.Join(s.ScriptArgs, ","))
+	}
+	appendFields(&s.Timing)
+	appendFields(&s.Evasion)
+	appendFields(&s.Output)
+	if len(s.Excludes) != 0 {
+		args = append(args, "--exclude", strings.Join(s.Excludes, ","))
+	}
+	return append(args, s.Targets...)
+}
+
+// fieldArgs 字段对应的参数，字段为零值时为空
+func fieldArgs(name string, field any) []string {
+	switch value := field.(type) {
+	case *bool:
+		if *value {
+			return []string{name}
+		}
+	case *string:
+		if *value != "" {
+			return []string{name, *value}
+		}
+	case *int:
+		//-vv、-ff
+		if *value > 0 {
+			return []string{"-" + strings.Repeat(name[1:], *value)}
+		}
+	case *Probe:
+		if value.Enabled {
+			return []string{name + value.Ports}
+		}
+	}
+	return nil
+}
+
+// field 参数对应的字段的指针，不是ScanSpec中的字段时返回nil
+func (s *ScanSpec) field(name string) any {
+	field, ok := specFields[name]
+	if !ok {
+		return nil
+	}
+	return reflect.ValueOf(s).Elem().FieldByIndex(field.index).Addr().Interface()
+}
+
+// Get 参数的值，参数不存在时返回false，有多个时返回第一个，-v、-d、-f返回级别
+func (s *ScanSpec) Get(name string) (string, bool) {
+	name, _ = levelOption(name, "")
+	switch name {
+	case "-p":
+		return s.Ports, s.Ports != ""
+	case "--exclude":
+		return strings.Join(s.Excludes, ","), len(s.Excludes) != 0
+	case "--script":
+		return strings.Join(s.Scripts, ","), len(s.Scripts) != 0
+	case "--script-args":
+		return strings.Join(s.ScriptArgs, ","), len(s.ScriptArgs) != 0
+	}
+	switch value := s.field(name).(type) {
+	case *bool:
+		return "", *value
+	case *string:
+		return *value, *value != ""
+	case *int:
+		return strconv.Itoa(*value), *value > 0
+	case *Probe:
+		return value.Ports, value.Enabled
+	}
+	for _, option := range s.Options {
+		if option.Name == name {
+			return option.Value, true
+		}
+	}
+	return "", false
+}
+
+// Has 是否有该参数
+func (s *ScanSpec) Has(name string) bool {
+	_, ok := s.Get(name)
+	return ok
+}
+
+// Set 设置参数的值，没有值的参数value为空，-v、-d、-f的value为级别，为空时为1；
+// 不是ScanSpec中的字段的参数，已有时替换第一个并删除其他的，否则添加到Options的最后
+func (s *ScanSpec) Set(name, value string) *ScanSpec {
+	name, value = levelOption(name, value)
+	switch name {
+	case "-p":
+		s.Ports = value
+		return s
+	case "--exclude":
+		s.Excludes = splitList(value)
+		return s
+	case "--script":
+		s.Scripts = splitList(value)
+		return s
+	case "--script-args":
+		s.ScriptArgs = splitList(value)
+		return s
+	}
+	switch field := s.field(name).(type) {
+	case *bool:
+		*field = true
+		return s
+	case *string:
+		*field = value
+		return s
+	case *int:
+		level, err := strconv.Atoi(value)
+		if err != nil {
+			level = 1
+		}
+		*field = level
+		return s
+	case *Probe:
+		*field = Probe{Enabled: true, Ports: value}
+		return s
+	}
+	s.set(Option{Name: name, Value: value})
+	return s
+}
+
+// set 替换Options中第一个同名参数并删除其他的，没有时添加
+func (s *ScanSpec) set(option Option) {
+	replaced := false
+	kept := s.Options[:0]
+	for _, o := range s.Options {
+		if o.Name != option.Name {
+			kept = append(kept, o)
+		} else if !replaced {
+			kept = append(kept, option)
+			replaced = true
+		}
+	}
+	if !replaced {
+		kept = append(kept, option)
+	}
+	s.Options = kept
+}
+
+// Remove 删除参数
+func (s *ScanSpec) Remove(name string) *ScanSpec {
+	name, _ = levelOption(name, "")
+	switch name {
+	case "-p":
+		s.Ports = ""
+		return s
+	case "--exclude":
+		s.Excludes = nil
+		return s
+	case "--script":
+		s.Scripts = nil
+		return s
+	case "--script-args":
+		s.ScriptArgs = nil
+		return s
+	}
+	if field, ok := specFields[name]; ok {
+		value := reflect.ValueOf(s).Elem().FieldByIndex(field.index)
+		value.Set(reflect.Zero(value.Type()))
+		return s
+	}
+	kept := s.Options[:0]
+	for _, option := range s.Options {
+		if option.Name != name {
+			kept = append(kept, option)
+		}
+	}
+	s.Options = kept
+	return s
+}
+
+// add 添加解析得到的参数，重复的-v、-d、-f累加级别
+func (s *ScanSpec) add(option Option) {
+	switch option.Name {
+	case "-p":
+		if s.Ports != "" {
+			option.Value = s.Ports + "," + option.Value
+		}
+		s.Ports = option.Value
+		return
+	case "--exclude":
+		s.Excludes = append(s.Excludes, splitList(option.Value)...)
+		return
+	case "--script":
+		s.Scripts = append(s.Scripts, splitList(option.Value)...)
+		return
+	case "--script-args":
+		s.ScriptArgs = append(s.ScriptArgs, splitList(option.Value)...)
+		return
+	}
+	name, value := levelOption(option.Name, option.Value)
+	if level, ok := s.field(name).(*int); ok {
+		n, err := strconv.Atoi(value)
+		switch {
+		case err != nil:
+			//-v、-f
+			*level++
+		case strings.Trim(option.Name[1:], option.Name[1:2]) == "":
+			//-vv
+			*level += n
+		default:
+			//-v3
+			*level = n
+		}
+		return
+	}
+	if s.field(name) != nil {
+		s.Set(name, value)
+		return
+	}
+	s.Options = append(s.Options, option)
+}
+
+// merge 用other中设置了的参数覆盖s中的参数
+func (s *ScanSpec) merge(other *ScanSpec) *ScanSpec {
+	if len(other.Targets) != 0 {
+		s.Targets = other.Targets
+	}
+	if len(other.Excludes) != 0 {
+		s.Excludes = other.Excludes
+	}
+	if other.Ports != "" {
+		s.Ports = other.Ports
+	}
+	if len(other.Scripts) != 0 {
+		s.Scripts = other.Scripts
+	}
+	if len(other.ScriptArgs) != 0 {
+		s.ScriptArgs = other.ScriptArgs
+	}
+	for _, field := range specFields {
+		value := reflect.ValueOf(other).Elem().FieldByIndex(field.index)
+		if !value.IsZero() {
+			reflect.ValueOf(s).Elem().FieldByIndex(field.index).Set(value)
+		}
+	}
+	for _, option := range other.Options {
+		s.set(option)
+	}
+	return s
+}
+
+// levelOption -vv、-v3等级别参数转换为-v和级别，其他参数原样返回
+func levelOption(name, value string) (string, string) {
+	if !levelRegexp.MatchString(name) || len(name) == 2 {
+		return name, value
+	}
+	level := name[2:]
+	if level[0] == name[1] {
+		level = strconv.Itoa(len(name) - 1)
+	}
+	return name[:2], level
+}
+
+// sameOption 是否为同一个参数，如-g和--source-port，-v和-vv
+func sameOption(a, b string) bool {
+	a, _ = levelOption(a, "")
+	b, _ = levelOption(b, "")
+	if fa, ok := specFields[a]; ok {
+		a = fa.names[0]
+	}
+	if fb, ok := specFields[b]; ok {
+		b = fb.names[0]
+	}
+	return a == b
+}
+
+// argGroup Args中的一个参数及其值，或一个目标
+type argGroup struct {
+	//在Args中的位置
+	start int
+	//原始的参数
+	tokens []string
+	//解析得到的参数，合并的参数展开为多个
+	options []Option
+	target  bool
+}
+
+// groupArgs 按参数分组，值原样保留，即使以-开头（如-oX -）；
+// 未知的参数后紧跟不以-开头的参数时，无法确定是值还是目标，作为该参数的值保留，保证重新生成的参数含义不变
+func groupArgs(args []string) []argGroup {
+	var groups []argGroup
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		group := argGroup{start: i, tokens: []string{arg}}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			group.target = true
+			groups = append(groups, group)
+			continue
+		}
+		expanded, ok := expandOption(arg)
+		if !ok {
+			options, _ := parseArgs([]string{arg})
+			if options[0].Value == "" && i+1 < len(args) && (!strings.HasPrefix(args[i+1], "-") || args[i+1] == "-") {
+				i++
+				options[0].Value = args[i]
+				group.tokens = append(group.tokens, args[i])
+			}
+			group.options = options
+			groups = append(groups, group)
+			continue
+		}
+		if spec, ok := optionSpecs[expanded[len(expanded)-1]]; ok && spec.value && !spec.optional && i+1 < len(args) {
+			i++
+			expanded = append(expanded, args[i])
+			group.tokens = append(group.tokens, args[i])
+		}
+		group.options, _ = parseArgs(expanded)
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// editArgs 在原位置用replacement替换第一个名为name的参数并删除其他的，replacement为nil时全部删除，
+// 没有修改的参数保持原样，返回是否找到该参数
+func editArgs(args []string, name string, replacement []string) ([]string, bool) {
+	var edited []string
+	found := false
+	for _, group := range groupArgs(args) {
+		matched := false
+		for _, option := range group.options {
+			matched = matched || sameOption(option.Name, name)
+		}
+		if !matched {
+			edited = append(edited, group.tokens...)
+			continue
+		}
+		for _, option := range group.options {
+			switch {
+			case !sameOption(option.Name, name):
+				edited = append(edited, option.args()...)
+			case !found && len(replacement) != 0:
+				//保留原来的写法，如-T4、--max-retries=2
+				if option.attached && len(replacement) == 2 {
+					replacement = Option{Name: replacement[0], Value: replacement[1], attached: true}.args()
+				}
+				edited = append(edited, replacement...)
+			}
+			found = found || sameOption(option.Name, name)
+		}
+	}
+	return edited, found
+}
+
+// splitList 按,分隔，引号、括号和花括号中的,不分隔，如 http.useragent="a,b",vulns.showall
+func splitList(value string) []string {
+	var items []string
+	var quote byte
+	depth, start := 0, 0
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(' || c == '{':
+			depth++
+		case c == ')' || c == '}':
+			depth--
+		case c == ',' && depth == 0:
+			if item := strings.TrimSpace(value[start:i]); item != "" {
+				items = append(items, item)
+			}
+			start = i + 1
+		}
+	}
+	if item := strings.TrimSpace(value[start:]); item != "" {
+		items = append(items, item)
+	}
+	return items
+}
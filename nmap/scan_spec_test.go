@@ -0,0 +1,105 @@
+package nmap
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestScanSpec(t *testing.T) {
+	scanner := NewNmap().AddTargets("scanme.nmap.org", "192.168.1.0/24").AddsS().AddsV().AddT(4).
+		Addp("22,80").AddPS("443").AddPn().Addscript("default", "http-title").Addscriptargs(`http.useragent="a,b",vulns.showall`).
+		Addexclude("192.168.1.1,192.168.1.2").AddoN("scan.nmap").AddD("RND:10").Addreason().AddsU().Addminrate("100")
+	scanner.Args = append(scanner.Args, "-p443", "--max-retries=2", "--iflist", "-vv", "-ff")
+	spec := scanner.Spec()
+
+	want := &ScanSpec{
+		Targets:    []string{"scanme.nmap.org", "192.168.1.0/24"},
+		Excludes:   []string{"192.168.1.1", "192.168.1.2"},
+		Ports:      "22,80,443",
+		Techniques: TechniqueSpec{SYN: true, UDP: true},
+		Discovery:  DiscoverySpec{TCPSyn: Probe{Enabled: true, Ports: "443"}, SkipDiscovery: true},
+		Timing:     TimingSpec{Template: "4", MinRate: "100", MaxRetries: "2"},
+		Evasion:    EvasionSpec{Decoys: "RND:10", Fragment: 2},
+		Scripts:    []string{"default", "http-title"},
+		ScriptArgs: []string{`http.useragent="a,b"`, "vulns.showall"},
+		Output:     OutputSpec{Normal: "scan.nmap", Reason: true, Iflist: true, Verbosity: 2},
+		Options:    []Option{{Name: "-sV"}},
+	}
+	if !reflect.DeepEqual(spec, want) {
+		t.Fatalf("expected %+v, but got %+v", want, spec)
+	}
+	args := []string{"-sS", "-sU", "-Pn", "-PS443", "-p", "22,80,443", "-sV",
+		"--script", "default,http-title", "--script-args", `http.useragent="a,b",vulns.showall`,
+		"-T", "4", "--max-retries", "2", "--min-rate", "100", "-ff", "-D", "RND:10", "-oN", "scan.nmap", "-vv", "--reason", "--iflist",
+		"--exclude", "192.168.1.1,192.168.1.2", "scanme.nmap.org", "192.168.1.0/24"}
+	if !reflect.DeepEqual(spec.Args(), args) {
+		t.Errorf("expected %q, but got %q", args, spec.Args())
+	}
+	if !reflect.DeepEqual(ParseArgs(spec.Args()), spec) {
+		t.Errorf("expected args round trip")
+	}
+
+	if value, ok := spec.Get("-T"); !ok || value != "4" || spec.Has("-O") {
+		t.Errorf("unexpected get -T %q %v", value, ok)
+	}
+	if value, ok := spec.Get("-v"); !ok || value != "2" {
+		t.Errorf("unexpected get -v %q %v", value, ok)
+	}
+	spec.Set("-T", "3").Remove("-sS").Set("-sT", "").Remove("--script-args").Set("-p", "1-1024").Remove("-D").Set("--source-port", "53")
+	if spec.Timing.Template != "3" || spec.Techniques.SYN || !spec.Techniques.Connect || spec.Evasion.SourcePort != "53" {
+		t.Errorf("unexpected spec %+v", spec)
+	}
+	scanner.SetSpec(spec).SetOption("-O", "").RemoveOption("--reason")
+	args = []string{"-sT", "-sU", "-Pn", "-PS443", "-p", "1-1024", "-sV",
+		"--script", "default,http-title", "-T", "3", "--max-retries", "2", "--min-rate", "100", "-ff", "-g", "53", "-oN", "scan.nmap", "-vv", "--iflist",
+		"--exclude", "192.168.1.1,192.168.1.2", "-O", "scanme.nmap.org", "192.168.1.0/24"}
+	if !reflect.DeepEqual(scanner.Args, args) {
+		t.Errorf("expected %q, but got %q", args, scanner.Args)
+	}
+}
+
+func TestSetOption(t *testing.T) {
+	//只修改对应的参数，未知的参数和其后的值保持原样
+	scanner := AddArgs(NewNmap(), "-sS", "--foo", "bar").AddTargets("10.0.0.1").SetOption("-T", "3")
+	want := []string{"-sS", "--foo", "bar", "-T", "3", "10.0.0.1"}
+	if !reflect.DeepEqual(scanner.Args, want) {
+		t.Fatalf("expected %q, but got %q", want, scanner.Args)
+	}
+	if spec := scanner.Spec(); !reflect.DeepEqual(spec.Options, []Option{{Name: "--foo", Value: "bar"}}) || !reflect.DeepEqual(spec.Targets, []string{"10.0.0.1"}) {
+		t.Errorf("expected --foo bar to be kept together, but got %+v", spec)
+	}
+	if args := scanner.Spec().Args(); !reflect.DeepEqual(args, []string{"-sS", "--foo", "bar", "-T", "3", "10.0.0.1"}) {
+		t.Errorf("unexpected spec args %q", args)
+	}
+
+	//保留原来的写法，合并的参数只展开修改的部分
+	scanner = AddArgs(NewNmap(), "10.0.0.1", "-sSV", "-T4", "--max-retries=2", "-p", "80", "-vv", "-p443").
+		SetOption("-T", "5").SetOption("--max-retries", "1").SetOption("-p", "22").SetOption("-v", "3").RemoveOption("-sS")
+	want = []string{"10.0.0.1", "-sV", "-T5", "--max-retries=1", "-p", "22", "-vvv"}
+	if !reflect.DeepEqual(scanner.Args, want) {
+		t.Errorf("expected %q, but got %q", want, scanner.Args)
+	}
+	scanner.SetOption("-g", "53").SetOption("--source-port", "80").RemoveOption("-vv")
+	want = []string{"-g", "80", "10.0.0.1", "-sV", "-T5", "--max-retries=1", "-p", "22"}
+	if !reflect.DeepEqual(scanner.Args, want) {
+		t.Errorf("expected %q, but got %q", want, scanner.Args)
+	}
+}
+
+func TestScanSpecOptionalValue(t *testing.T) {
+	//-PS等参数的端口只能紧跟参数，-PS 10.0.0.1中的10.0.0.1为目标
+	spec := ParseArgs([]string{"-sn", "-PS", "-PA", "-PU53", "10.0.0.1"})
+	want := DiscoverySpec{PingScan: true, TCPSyn: Probe{Enabled: true}, TCPAck: Probe{Enabled: true}, UDP: Probe{Enabled: true, Ports: "53"}}
+	if !reflect.DeepEqual(spec.Discovery, want) || !reflect.DeepEqual(spec.Targets, []string{"10.0.0.1"}) {
+		t.Fatalf("unexpected spec %+v", spec)
+	}
+	spec.Set("-PS", "22,80")
+	args := []string{"-sn", "-PS22,80", "-PA", "-PU53", "10.0.0.1"}
+	if !reflect.DeepEqual(spec.Args(), args) {
+		t.Fatalf("expected %q, but got %q", args, spec.Args())
+	}
+	scanner, err := ParseCommand("nmap -PS 10.0.0.1")
+	if err != nil || !reflect.DeepEqual(scanner.Spec().Targets, []string{"10.0.0.1"}) {
+		t.Fatalf("unexpected targets %v, %v", scanner.Spec().Targets, err)
+	}
+}
@@ -0,0 +1,149 @@
+package nmap
+
+import (
+	"strconv"
+	"strings"
+)
+
+// NSE结构化输出的通用访问
+//
+// table/elem转换为 map[string]any、[]any、string 组成的树：
+// 子节点都没有key时为list，否则为map，map中没有key的子节点以下标（从1开始，与lua一致）作为key
+
+// Value 结构化输出转换后的树，没有结构化输出时为空map
+func (s Script) Value() any {
+	return s.table().Value()
+}
+
+// Map 结构化输出的顶层map
+func (s Script) Map() map[string]any {
+	return s.table().Map()
+}
+
+// List 结构化输出的顶层list，忽略子节点的key
+func (s Script) List() []any {
+	return s.table().List()
+}
+
+// Get 按路径获取结构化输出，如 subject.commonName、extensions.1.name
+//
+// key中本身包含.时（如dns-nsid的id.server）也可以直接使用
+func (s Script) Get(path string) (any, bool) {
+	return s.table().Get(path)
+}
+
+// GetString 按路径获取elem的值，不存在或不是elem时为空
+func (s Script) GetString(path string) string {
+	return s.table().GetString(path)
+}
+
+func (s Script) table() Table {
+	return Table{Table: s.Table, Elem: s.Elem}
+}
+
+// Value table转换后的树
+func (t Table) Value() any {
+	var keyed bool
+	for _, elem := range t.Elem {
+		keyed = keyed || elem.Key != ""
+	}
+	for _, table := range t.Table {
+		keyed = keyed || table.Key != ""
+	}
+	if !keyed && len(t.Elem)+len(t.Table) > 0 {
+		return t.List()
+	}
+	return t.Map()
+}
+
+// Map table的子节点，没有key的子节点以下标作为key
+func (t Table) Map() map[string]any {
+	value := make(map[string]any, len(t.Elem)+len(t.Table))
+	index := 0
+	key := func(k string) string {
+		if k != "" {
+			return k
+		}
+		index++
+		return strconv.Itoa(index)
+	}
+	for _, elem := range t.Elem {
+		value[key(elem.Key)] = elem.Text
+	}
+	for _, table := range t.Table {
+		value[key(table.Key)] = table.Value()
+	}
+	return value
+}
+
+// List table的子节点，忽略key，elem在前table在后
+func (t Table) List() []any {
+	value := make([]any, 0, len(t.Elem)+len(t.Table))
+	for _, elem := range t.Elem {
+		value = append(value, elem.Text)
+	}
+	for _, table := range t.Table {
+		value = append(value, table.Value())
+	}
+	return value
+}
+
+// Get 按路径获取子节点
+func (t Table) Get(path string) (any, bool) {
+	if path == "" {
+		return t.Value(), true
+	}
+	return lookup(t.Value(), strings.Split(path, "."))
+}
+
+// GetString 按路径获取elem的值
+func (t Table) GetString(path string) string {
+	value, _ := t.Get(path)
+	text, _ := value.(string)
+	return text
+}
+
+// lookup 优先匹配最长的key，以支持包含.的key
+func lookup(value any, path []string) (any, bool) {
+	if len(path) == 0 {
+		return value, true
+	}
+	switch v := value.(type) {
+	case map[string]any:
+		for n := len(path); n > 0; n-- {
+			child, ok := v[strings.Join(path[:n], ".")]
+			if !ok {
+				continue
+			}
+			if result, ok := lookup(child, path[n:]); ok {
+				return result, true
+			}
+		}
+	case []any:
+		index, err := strconv.Atoi(path[0])
+		if err == nil && index >= 1 && index <= len(v) {
+			return lookup(v[index-1], path[1:])
+		}
+	}
+	return nil, false
+}
+
+// elem 获取指定key的elem的值
+func (t Table) elem(key string) string {
+	for _, elem := range t.Elem {
+		if elem.Key == key {
+			return elem.Text
+		}
+	}
+	return ""
+}
+
+// table 获取指定key的子table
+func (t Table) table(key string) Table {
+	for _, table := range t.Table {
+		if table.Key == key {
+			return table
+		}
+	}
+	return Table{}
+}
@@ -161,7 +161,7 @@ func (receiver *nmap) Addscriptupdatedb() *nmap {
 // scripts, you can use this as a preview of what scripts will be run for a specification,
 // for example with nmap --script-help default.
 func (receiver *nmap) Addscripthelp(scripts ...string) *nmap {
-	receiver.outputType = "none"
+	receiver.noXML = true
 	scriptList := strings.Join(scripts, ",")
 	return AddArgs(receiver, "--script-help", scriptList)
 }
@@ -0,0 +1,106 @@
+package nmap
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestScriptValue(t *testing.T) {
+	result := loadXML(t, "testdata/scanme.xml")
+	ports := result.Host[0].Ports[0].Port
+	sslCert := ports[2].Script[0]
+	for path, expected := range map[string]string{
+		"subject.commonName":  "scanme.nmap.org",
+		"issuer.countryName":  "US",
+		"extensions.2.name":   "X509v3 Basic Constraints",
+		"validity.notAfter":   "2022-05-30T00:00:00",
+		"pubkey.bits":         "2048",
+		"extensions.3.name":   "",
+		"subject.nonexistent": "",
+	} {
+		if value := sslCert.GetString(path); value != expected {
+			t.Errorf("%s: expected %q, but got %q", path, expected, value)
+		}
+	}
+	if _, ok := sslCert.Get("extensions"); !ok {
+		t.Errorf("expected extensions")
+	}
+	if extensions, _ := sslCert.Get("extensions"); len(extensions.([]any)) != 2 {
+		t.Errorf("expected extensions list, but got %v", extensions)
+	}
+
+	//key中包含.
+	dnsNSID := result.Host[0].HostScript[0]
+	if value := dnsNSID.GetString("id.server"); value != "scanme-ns1" {
+		t.Errorf("expected scanme-ns1, but got %q", value)
+	}
+	//没有key的table为list
+	sshHostKey := ports[0].Script[0]
+	if keys := sshHostKey.List(); len(keys) != 4 || sshHostKey.GetString("4.type") != "ssh-ed25519" {
+		t.Errorf("unexpected ssh-hostkey %v", keys)
+	}
+	if value := sshHostKey.Map()["1"]; !reflect.DeepEqual(value, sshHostKey.List()[0]) {
+		t.Errorf("expected index key, but got %v", value)
+	}
+	if value := (Script{Id: "banner", Output: "x"}).Value(); !reflect.DeepEqual(value, map[string]any{}) {
+		t.Errorf("expected empty map, but got %v", value)
+	}
+}
+
+func TestParseScript(t *testing.T) {
+	result := loadXML(t, "testdata/scanme.xml")
+	ports := result.Host[0].Ports[0].Port
+
+	cert, err := ParseSSLCert(ports[2].Script[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert.Subject["commonName"] != "scanme.nmap.org" || cert.Issuer["organizationName"] != "Let's Encrypt" ||
+		cert.PubKey.Bits != 2048 || len(cert.Extensions) != 2 || !cert.Extensions[1].Critical ||
+		!cert.NotAfter.Equal(time.Date(2022, 5, 30, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected ssl-cert %+v", cert)
+	}
+
+	ciphers, err := ParseSSLEnumCiphers(ports[2].Script[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ciphers.Protocols) != 2 || ciphers.Protocols[0].Version != "TLSv1.2" || len(ciphers.Protocols[0].Ciphers) != 2 ||
+		ciphers.Protocols[0].Compressors[0] != "NULL" || ciphers.Protocols[1].Ciphers[0].Name != "TLS_AKE_WITH_AES_256_GCM_SHA384" ||
+		ciphers.LeastStrength != "A" {
+		t.Errorf("unexpected ssl-enum-ciphers %+v", ciphers)
+	}
+
+	title, err := ParseHTTPTitle(ports[1].Script[0])
+	if err != nil || title.Title != "Go ahead and ScanMe!" {
+		t.Errorf("unexpected http-title %+v %v", title, err)
+	}
+	headers, err := ParseHTTPHeaders(ports[1].Script[1])
+	if err != nil || len(headers.Headers) != 6 || headers.Get("server") != "Apache/2.4.7 (Ubuntu)" || headers.RequestType != "HEAD" {
+		t.Errorf("unexpected http-headers %+v %v", headers, err)
+	}
+	keys, err := ParseSSHHostKey(ports[0].Script[0])
+	if err != nil || len(keys) != 4 || keys[1].Type != "ssh-rsa" || keys[1].Bits != 2048 {
+		t.Errorf("unexpected ssh-hostkey %+v %v", keys, err)
+	}
+	banner, err := ParseBanner(ports[0].Script[1])
+	if err != nil || banner != "SSH-2.0-OpenSSH_6.6.1p1 Ubuntu-2ubuntu2.13" {
+		t.Errorf("unexpected banner %q %v", banner, err)
+	}
+	nsid, err := ParseDNSNSID(result.Host[0].HostScript[0])
+	if err != nil || nsid.IDServer != "scanme-ns1" || nsid.BindVersion != "9.16.1-Ubuntu" {
+		t.Errorf("unexpected dns-nsid %+v %v", nsid, err)
+	}
+	smb, err := ParseSMBOSDiscovery(result.Host[1].HostScript[0])
+	if err != nil || smb.Server != "FILESERVER" || smb.Domain != "corp.example" || smb.Workgroup != "CORP" {
+		t.Errorf("unexpected smb-os-discovery %+v %v", smb, err)
+	}
+
+	if _, err = ParseSSLCert(ports[0].Script[1]); err == nil {
+		t.Errorf("expected script id error")
+	}
+	if _, err = ParseSSHHostKey(Script{Id: "ssh-hostkey"}); err == nil {
+		t.Errorf("expected no structured output error")
+	}
+}
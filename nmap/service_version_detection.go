@@ -43,7 +43,8 @@ func (receiver *nmap) Addallports() *nmap {
 //When performing a version scan (-sV), Nmap sends a series of probes, each of which is assigned a rarity value between one and nine. The lower-numbered probes are effective against a wide variety of common services, while the higher-numbered ones are rarely useful. The intensity level specifies which probes should be applied. The higher the number, the more likely it is the service will be correctly identified. However, high intensity scans take longer. The intensity must be between 0 and 9. The default is 7. When a probe is registered to the target port via the nmap-service-probes ports directive, that probe is tried regardless of intensity level. This ensures that the DNS probes will always be attempted against any open port 53, the SSL probe will be done against 443, etc.
 func (receiver *nmap) Addversionintensity(level int) *nmap {
 	if level < 0 || level > 9 {
-		panic("level scope: 1[0-9]")
+		receiver.setErr(&ValidationError{Kind: ValidationRange, Option: "--version-intensity", Message: "level scope: [0-9]"})
+		return receiver
 	}
 	return AddArgs(receiver, "--version-intensity", strconv.Itoa(level))
 }
@@ -0,0 +1,101 @@
+package nmap
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Excel和csv共用的表头和行
+
+var (
+	//hosthint表，没有hosthint时为host
+	hostHeader = []string{"address", "hostnames", "state", "reason"}
+	//host And Ports表，前4列为host，_state和_reason为host的状态
+	portHeader = []string{"address", "hostnames", "_state", "_reason", "port", "protocol", "state", "service", "product", "version", "cpe", "confidence", "reason", "nseresult"}
+)
+
+// nseSeparator nseresult列中多个脚本结果的分隔符
+var nseSeparator = strings.Repeat("&", 20)
+
+// hostRows hosthint表的行，没有hosthint时使用host
+func hostRows(result *NmapXMLResult) [][]any {
+	var rows [][]any
+	for _, hosthint := range result.HostHint {
+		rows = append(rows, hostRow(hosthint.Address, hosthint.Hostnames, hosthint.Status))
+	}
+	if len(result.HostHint) != 0 {
+		return rows
+	}
+	for _, host := range result.Hosts() {
+		rows = append(rows, hostRow(host.Address, host.Hostnames, host.Status))
+	}
+	return rows
+}
+
+func hostRow(addrs []Address, hostnames []Hostname, status Status) []any {
+	row := make([]any, len(hostHeader))
+	if len(addrs) != 0 {
+		row[0] = strings.Join(addrList(addrs), "\n")
+	}
+	if len(hostnames) != 0 {
+		names := make([]string, 0, len(hostnames))
+		for _, hostname := range hostnames {
+			names = append(names, hostname.Name)
+		}
+		row[1] = strings.Join(names, "\n")
+	}
+	row[2] = status.State
+	row[3] = status.Reason
+	return row
+}
+
+// portRowGroups host And Ports表的行，每个host一组，每个端口一行，host没有端口时只有host的一行
+func portRowGroups(result *NmapXMLResult) [][][]any {
+	var groups [][][]any
+	for _, host := range result.Hosts() {
+		var group [][]any
+		ports := host.PortList()
+		if len(ports) == 0 {
+			group = append(group, portRow(host, nil))
+		}
+		for _, port := range ports {
+			group = append(group, portRow(host, port))
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+func portRow(host *Host, port *Port) []any {
+	row := make([]any, len(portHeader))
+	copy(row, hostRow(host.Address, host.Hostnames, host.Status))
+	if port == nil {
+		return row
+	}
+	row[4] = port.PortId
+	row[5] = port.Protocol
+	row[6] = port.State.State
+	row[7] = port.Service.Name
+	row[8] = port.Service.Product
+	row[9] = port.Service.Version
+	if len(port.Service.CPE) != 0 {
+		row[10] = strings.Join(port.Service.CPE, "\n")
+	}
+	row[11] = port.Service.Conf
+	row[12] = port.State.Reason
+	nseOutput := make([]string, 0)
+	for _, script := range port.Script {
+		nseOutput = append(append(append(nseOutput, script.Id), script.Output), nseSeparator)
+	}
+	nse := strings.Join(nseOutput, "\n")
+	row[13] = strings.TrimSuffix(nse, nseSeparator)
+	return row
+}
+
+// cellString 单元格的文本
+func cellString(value any) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,101 @@
+package nmap
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sink 导出结果的输出目标，如文件、内存
+type Sink interface {
+	// Create 创建名为name的输出，返回写入的位置（如文件路径）
+	Create(name string) (io.WriteCloser, string, error)
+}
+
+// FilePolicy 文件已存在时的处理方式
+type FilePolicy int
+
+const (
+	//覆盖已存在的文件
+	Overwrite FilePolicy = iota
+	//追加到已存在的文件，适用于txt、jsonl等按行的格式
+	Append
+	//文件名后增加时间戳，如 Result_20220418_100000.xlsx，不覆盖已存在的文件
+	Timestamp
+	//文件已存在时返回错误
+	Exclusive
+)
+
+// FileSink 写入文件，Dir为空时为当前目录，零值为覆盖已存在的文件
+type FileSink struct {
+	Dir    string
+	Policy FilePolicy
+	//文件权限，为0时使用0644
+	Perm os.FileMode
+}
+
+func (s FileSink) Create(name string) (io.WriteCloser, string, error) {
+	target := filepath.Join(s.Dir, name)
+	perm := s.Perm
+	if perm == 0 {
+		perm = 0644
+	}
+	switch s.Policy {
+	case Append:
+		file, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_APPEND, perm)
+		return file, target, err
+	case Exclusive:
+		file, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+		return file, target, err
+	case Timestamp:
+		ext := filepath.Ext(target)
+		base := strings.TrimSuffix(target, ext) + "_" + time.Now().Format("20060102_150405")
+		for i := 0; ; i++ {
+			target = base + ext
+			if i != 0 {
+				target = base + "_" + strconv.Itoa(i) + ext
+			}
+			file, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+			if !os.IsExist(err) {
+				return file, target, err
+			}
+		}
+	default:
+		file, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+		return file, target, err
+	}
+}
+
+// MemorySink 写入内存，key为名称，用于测试或打包
+type MemorySink map[string]*bytes.Buffer
+
+func (s MemorySink) Create(name string) (io.WriteCloser, string, error) {
+	buffer := &bytes.Buffer{}
+	s[name] = buffer
+	return nopCloser{buffer}, name, nil
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// writeSink 创建输出并写入，返回写入的位置
+func writeSink(sink Sink, name string, write func(w io.Writer) error) (string, error) {
+	w, target, err := sink.Create(name)
+	if err != nil {
+		return "", err
+	}
+	if err := write(w); err != nil {
+		w.Close()
+		return target, err
+	}
+	return target, w.Close()
+}
@@ -38,6 +38,6 @@ const (
 	Closed         PortStatus = "closed"
 	Filtered       PortStatus = "filtered"
 	Unfiltered     PortStatus = "unfiltered"
-	OpenFiltered   PortStatus = "Open/Filtered"
-	ClosedFiltered PortStatus = "Closed/Filtered"
+	OpenFiltered   PortStatus = "open|filtered"
+	ClosedFiltered PortStatus = "closed|filtered"
 )
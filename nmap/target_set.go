@@ -0,0 +1,668 @@
+package nmap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"math"
+	"math/bits"
+	"net/netip"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// TargetKind 目标的类型
+type TargetKind string
+
+const (
+	//单个IP，如192.168.1.1、fe80::1%eth0
+	TargetIP TargetKind = "ip"
+	//CIDR，如192.168.1.0/24、2001:db8::/120
+	TargetCIDR TargetKind = "cidr"
+	//八位字节范围，如10.0-3.1-254.*、192.168.3-5,7.1
+	TargetRange TargetKind = "range"
+	//主机名，可带CIDR，如scanme.nmap.org、microsoft.com/24，不解析
+	TargetHostname TargetKind = "hostname"
+)
+
+// 展开八位字节范围时最多的连续地址段，如0-255.0-255.13.37为65536段
+const maxOctetRanges = 1 << 20
+
+var (
+	//八位字节范围，每段为,分隔的数字、范围、-或*
+	octetRangeRegexp = regexp.MustCompile(`^[0-9*,-]+(\.[0-9*,-]+){3}$`)
+	hostnameRegexp   = regexp.MustCompile(`^[A-Za-z0-9_]([A-Za-z0-9_.-]*[A-Za-z0-9_.])?$`)
+)
+
+// TargetSpec 一个目标说明，如 192.168.1.0/24、10.0-3.1-254.*、scanme.nmap.org
+type TargetSpec struct {
+	//原始的目标说明
+	Spec string     `json:"spec"`
+	Kind TargetKind `json:"kind"`
+	//Kind为TargetHostname时的主机名，不含/<numbits>
+	Host string `json:"host,omitempty"`
+	//CIDR的位数，没有时为-1
+	Bits int `json:"bits"`
+	//包含的地址段，主机名为空
+	ranges []addrRange
+}
+
+// addrRange 连续的地址段[from, to]，from和to为同一地址族
+type addrRange struct {
+	from, to netip.Addr
+}
+
+// ParseTarget 解析一个目标说明，格式与nmap命令行相同，不支持的格式返回ErrInvalidTarget
+func ParseTarget(spec string) (*TargetSpec, error) {
+	target := &TargetSpec{Spec: spec, Bits: -1}
+	invalid := func(reason string) (*TargetSpec, error) {
+		return nil, errors.Wrapf(ErrInvalidTarget, "%q %s", spec, reason)
+	}
+	host := spec
+	if i := strings.LastIndex(spec, "/"); i != -1 {
+		n, err := strconv.Atoi(spec[i+1:])
+		if err != nil || n < 0 {
+			return invalid("has an invalid CIDR prefix length")
+		}
+		host, target.Bits = spec[:i], n
+	}
+	if host == "" {
+		return invalid("is empty")
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if addr.Is4In6() {
+			addr = addr.Unmap()
+		}
+		if target.Bits == -1 {
+			target.Kind = TargetIP
+			target.ranges = []addrRange{{addr, addr}}
+			return target, nil
+		}
+		if target.Bits > addr.BitLen() {
+			return invalid("has a CIDR prefix length greater than " + strconv.Itoa(addr.BitLen()))
+		}
+		prefix := netip.PrefixFrom(addr.WithZone(""), target.Bits).Masked()
+		target.Kind = TargetCIDR
+		target.ranges = []addrRange{{prefix.Addr(), lastAddr(prefix)}}
+		return target, nil
+	}
+	if octetRangeRegexp.MatchString(host) {
+		if target.Bits != -1 {
+			return invalid("cannot combine octet ranges with CIDR")
+		}
+		ranges, err := octetRanges(host)
+		if err != nil {
+			return invalid(err.Error())
+		}
+		target.Kind = TargetRange
+		target.ranges = ranges
+		return target, nil
+	}
+	//只有数字和.的不是主机名，如300.1.1.1、10.0.0
+	if strings.Trim(host, "0123456789.") == "" || strings.Contains(host, ":") || !hostnameRegexp.MatchString(host) {
+		return invalid("is not a valid IP address, network or hostname")
+	}
+	if target.Bits > 128 {
+		return invalid("has a CIDR prefix length greater than 128")
+	}
+	target.Kind = TargetHostname
+	target.Host = strings.ToLower(host)
+	return target, nil
+}
+
+// Count 目标包含的地址数，主机名为1，主机名/<numbits>按IPv4计算（位数大于32时按IPv6），超过uint64时为math.MaxUint64
+func (t *TargetSpec) Count() uint64 {
+	if t.Kind == TargetHostname {
+		if t.Bits == -1 {
+			return 1
+		}
+		if t.Bits <= 32 {
+			return 1 << (32 - t.Bits)
+		}
+		return pow2(128 - t.Bits)
+	}
+	var count uint64
+	for _, r := range t.ranges {
+		count = addCount(count, r.count())
+	}
+	return count
+}
+
+// octetRanges 解析八位字节范围，如10.0-3.1-254.*，每个八位字节可以是,分隔的数字和范围，范围两边省略时为0和255
+func octetRanges(host string) ([]addrRange, error) {
+	var octets [4][][2]int
+	for i, part := range strings.Split(host, ".") {
+		for _, item := range strings.Split(part, ",") {
+			lo, hi := 0, 255
+			switch {
+			case item == "*" || item == "-":
+			case strings.Contains(item, "-"):
+				left, right, _ := strings.Cut(item, "-")
+				var err1, err2 error
+				if left != "" {
+					lo, err1 = strconv.Atoi(left)
+				}
+				if right != "" {
+					hi, err2 = strconv.Atoi(right)
+				}
+				if err1 != nil || err2 != nil {
+					return nil, errors.Errorf("has an invalid octet range %q", item)
+				}
+			default:
+				n, err := strconv.Atoi(item)
+				if err != nil {
+					return nil, errors.Errorf("has an invalid octet %q", item)
+				}
+				lo, hi = n, n
+			}
+			if lo < 0 || hi > 255 || lo > hi {
+				return nil, errors.Errorf("has an invalid octet range %q", item)
+			}
+			octets[i] = append(octets[i], [2]int{lo, hi})
+		}
+		octets[i] = mergeOctets(octets[i])
+	}
+	//最后一个不是0-255的八位字节，其后的八位字节连续
+	last := 0
+	for i := 3; i > 0; i-- {
+		if len(octets[i]) != 1 || octets[i][0] != [2]int{0, 255} {
+			last = i
+			break
+		}
+	}
+	combinations := len(octets[last])
+	for i := 0; i < last; i++ {
+		values := 0
+		for _, o := range octets[i] {
+			values += o[1] - o[0] + 1
+		}
+		combinations *= values
+		if combinations > maxOctetRanges {
+			return nil, errors.Errorf("expands to more than %d address ranges", maxOctetRanges)
+		}
+	}
+	ranges := make([]addrRange, 0, combinations)
+	var prefix [4]byte
+	var walk func(i int)
+	walk = func(i int) {
+		if i == last {
+			for _, o := range octets[i] {
+				from, to := prefix, prefix
+				from[i], to[i] = byte(o[0]), byte(o[1])
+				for j := i + 1; j < 4; j++ {
+					from[j], to[j] = 0, 255
+				}
+				ranges = append(ranges, addrRange{netip.AddrFrom4(from), netip.AddrFrom4(to)})
+			}
+			return
+		}
+		for _, o := range octets[i] {
+			for v := o[0]; v <= o[1]; v++ {
+				prefix[i] = byte(v)
+				walk(i + 1)
+			}
+		}
+	}
+	walk(0)
+	return ranges, nil
+}
+
+// mergeOctets 排序并合并重叠的八位字节范围
+func mergeOctets(octets [][2]int) [][2]int {
+	sort.Slice(octets, func(i, j int) bool { return octets[i][0] < octets[j][0] })
+	merged := octets[:1]
+	for _, o := range octets[1:] {
+		last := &merged[len(merged)-1]
+		if o[0] <= last[1]+1 {
+			if o[1] > last[1] {
+				last[1] = o[1]
+			}
+			continue
+		}
+		merged = append(merged, o)
+	}
+	return merged
+}
+
+// TargetSet 去重后的目标集合，IP按地址排序合并，主机名按添加顺序，可减去排除的目标后展开、计数和分片
+//
+//	set, err := scanner.TargetSet() //目标、-iL减去--exclude、--excludefile
+//	set.Count()                      //扫描的地址数
+//	set.Limit(65536)                 //超过时返回ErrTooManyTargets
+//	set.Shard(4)                     //分为4个目标集合，分别扫描
+//
+// 主机名不解析，只与相同的主机名去重和排除
+type TargetSet struct {
+	ranges []addrRange
+	hosts  []*TargetSpec
+}
+
+// NewTargetSet 解析目标说明，每个参数可以包含空格分隔的多个目标说明
+func NewTargetSet(specs ...string) (*TargetSet, error) {
+	set := &TargetSet{}
+	if err := set.Add(specs...); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// ReadTargetSet 读取-iL格式的目标文件，目标以空格、tab或换行分隔，#开头到行尾为注释
+func ReadTargetSet(r io.Reader) (*TargetSet, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return NewTargetSet(lines...)
+}
+
+// LoadTargetSet 读取-iL格式的目标文件
+func LoadTargetSet(path string) (*TargetSet, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	set, err := ReadTargetSet(f)
+	return set, errors.Wrap(err, path)
+}
+
+// TargetSet 扫描的目标集合：目标和-iL文件中的目标减去--exclude和--excludefile中的目标，
+// 不支持-iL -（标准输入）和-iR，返回ErrInvalidTarget；
+// 未知的参数后紧跟不以-开头的参数时，无法确定是值还是目标，返回ErrUnknownOption
+func (receiver *nmap) TargetSet() (*TargetSet, error) {
+	var options []Option
+	var targets []string
+	for _, group := range groupArgs(receiver.Args) {
+		if group.target {
+			targets = append(targets, group.tokens...)
+			continue
+		}
+		for _, option := range group.options {
+			if !isKnownOption(option.Name) && option.Value != "" {
+				return nil, errors.Wrapf(ErrUnknownOption, "%s: cannot tell whether %s is its value or a target", option.Name, option.Value)
+			}
+		}
+		options = append(options, group.options...)
+	}
+	set, err := NewTargetSet(targets...)
+	if err != nil {
+		return nil, err
+	}
+	excludes := &TargetSet{}
+	for _, option := range options {
+		var loaded *TargetSet
+		switch option.Name {
+		case "-iL", "--excludefile":
+			if option.Value == "-" {
+				return nil, errors.Wrapf(ErrInvalidTarget, "%s - (stdin) cannot be read", option.Name)
+			}
+			loaded, err = LoadTargetSet(option.Value)
+		case "--exclude":
+			//--exclude按,分隔，不支持带,的八位字节范围
+			loaded, err = NewTargetSet(strings.Split(option.Value, ",")...)
+		case "-iR":
+			return nil, errors.Wrap(ErrInvalidTarget, "-iR random targets cannot be expanded")
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if option.Name == "-iL" {
+			set.merge(loaded)
+		} else {
+			excludes.merge(loaded)
+		}
+	}
+	return set.Subtract(excludes), nil
+}
+
+// Add 添加目标说明，每个参数可以包含空格分隔的多个目标说明
+func (s *TargetSet) Add(specs ...string) error {
+	var ranges []addrRange
+	for _, spec := range specs {
+		for _, field := range strings.Fields(spec) {
+			target, err := ParseTarget(field)
+			if err != nil {
+				return err
+			}
+			s.addHost(target)
+			ranges = append(ranges, target.ranges...)
+		}
+	}
+	s.ranges = mergeRanges(append(s.ranges, ranges...))
+	return nil
+}
+
+func (s *TargetSet) addHost(target *TargetSpec) {
+	if target.Kind != TargetHostname {
+		return
+	}
+	for _, host := range s.hosts {
+		if host.Host == target.Host && host.Bits == target.Bits {
+			return
+		}
+	}
+	s.hosts = append(s.hosts, target)
+}
+
+func (s *TargetSet) merge(other *TargetSet) {
+	for _, host := range other.hosts {
+		s.addHost(host)
+	}
+	s.ranges = mergeRanges(append(s.ranges, other.ranges...))
+}
+
+// Subtract 返回减去excludes后的目标集合，主机名只排除相同的主机名
+func (s *TargetSet) Subtract(excludes *TargetSet) *TargetSet {
+	result := &TargetSet{}
+	for _, host := range s.hosts {
+		excluded := false
+		for _, exclude := range excludes.hosts {
+			if host.Host == exclude.Host && (exclude.Bits == -1 || host.Bits == exclude.Bits) {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			result.hosts = append(result.hosts, host)
+		}
+	}
+	i := 0
+	for _, r := range s.ranges {
+		for i < len(excludes.ranges) && excludes.ranges[i].to.Less(r.from) {
+			i++
+		}
+		from, done := r.from, false
+		for j := i; j < len(excludes.ranges) && !done; j++ {
+			exclude := excludes.ranges[j]
+			if r.to.Less(exclude.from) {
+				break
+			}
+			if from.Less(exclude.from) {
+				result.ranges = append(result.ranges, addrRange{from, exclude.from.Prev()})
+			}
+			if !exclude.to.Less(r.to) {
+				done = true
+			} else if exclude.to.Compare(from) >= 0 {
+				from = exclude.to.Next()
+			}
+		}
+		if !done {
+			result.ranges = append(result.ranges, addrRange{from, r.to})
+		}
+	}
+	return result
+}
+
+// Count 去重后的地址数，主机名的计算见TargetSpec.Count，超过uint64时为math.MaxUint64
+func (s *TargetSet) Count() uint64 {
+	var count uint64
+	for _, r := range s.ranges {
+		count = addCount(count, r.count())
+	}
+	for _, host := range s.hosts {
+		count = addCount(count, host.Count())
+	}
+	return count
+}
+
+// Limit 地址数超过max时返回ErrTooManyTargets，用于在扫描前拒绝范围过大的目标
+func (s *TargetSet) Limit(max uint64) error {
+	if count := s.Count(); count > max {
+		return errors.Wrapf(ErrTooManyTargets, "%d addresses, limit %d", count, max)
+	}
+	return nil
+}
+
+// Contains 是否包含地址或主机名
+func (s *TargetSet) Contains(host string) bool {
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		host = strings.ToLower(host)
+		for _, h := range s.hosts {
+			if h.Host == host && h.Bits == -1 {
+				return true
+			}
+		}
+		return false
+	}
+	addr = addr.Unmap()
+	i := sort.Search(len(s.ranges), func(i int) bool { return !s.ranges[i].to.Less(addr) })
+	return i < len(s.ranges) && !addr.Less(s.ranges[i].from)
+}
+
+// Each 按顺序遍历所有地址和主机名，fn返回false时停止，主机名/<numbits>不展开
+func (s *TargetSet) Each(fn func(target string) bool) {
+	for _, r := range s.ranges {
+		for addr := r.from; ; addr = addr.Next() {
+			if !fn(addr.String()) {
+				return
+			}
+			if addr == r.to {
+				break
+			}
+		}
+	}
+	for _, host := range s.hosts {
+		if !fn(host.String()) {
+			return
+		}
+	}
+}
+
+// Expand 展开为地址和主机名的列表，范围很大时（如IPv6 /64）使用Count检查或使用Each
+func (s *TargetSet) Expand() []string {
+	var targets []string
+	s.Each(func(target string) bool {
+		targets = append(targets, target)
+		return true
+	})
+	return targets
+}
+
+// Targets 最少的目标说明，连续的地址转换为CIDR，可直接用于AddTargets
+func (s *TargetSet) Targets() []string {
+	var targets []string
+	for _, r := range s.ranges {
+		//带zone的IPv6地址只能是单个地址
+		if r.from == r.to {
+			targets = append(targets, r.from.String())
+			continue
+		}
+		for _, prefix := range r.prefixes() {
+			if prefix.IsSingleIP() {
+				targets = append(targets, prefix.Addr().String())
+			} else {
+				targets = append(targets, prefix.String())
+			}
+		}
+	}
+	for _, host := range s.hosts {
+		targets = append(targets, host.String())
+	}
+	return targets
+}
+
+// Shard 按地址顺序分为地址数相近的最多n个目标集合，主机名/<numbits>不拆分
+func (s *TargetSet) Shard(n int) []*TargetSet {
+	if n < 1 {
+		n = 1
+	}
+	total := s.Count()
+	size := func(i int) uint64 {
+		if uint64(i) < total%uint64(n) {
+			return total/uint64(n) + 1
+		}
+		return total / uint64(n)
+	}
+	var shards []*TargetSet
+	shard, filled := &TargetSet{}, uint64(0)
+	next := func() {
+		if filled >= size(len(shards)) && len(shards) < n-1 {
+			shards = append(shards, shard)
+			shard, filled = &TargetSet{}, 0
+		}
+	}
+	for _, r := range s.ranges {
+		for {
+			need := size(len(shards)) - filled
+			if len(shards) == n-1 || r.count() <= need {
+				shard.ranges = append(shard.ranges, r)
+				filled += r.count()
+				next()
+				break
+			}
+			to := addAddr(r.from, need-1)
+			shard.ranges = append(shard.ranges, addrRange{r.from, to})
+			filled += need
+			next()
+			r.from = to.Next()
+		}
+	}
+	for _, host := range s.hosts {
+		shard.hosts = append(shard.hosts, host)
+		filled += host.Count()
+		next()
+	}
+	if len(shard.ranges) != 0 || len(shard.hosts) != 0 {
+		shards = append(shards, shard)
+	}
+	return shards
+}
+
+func (t *TargetSpec) String() string {
+	if t.Kind != TargetHostname {
+		return t.Spec
+	}
+	if t.Bits == -1 {
+		return t.Host
+	}
+	return t.Host + "/" + strconv.Itoa(t.Bits)
+}
+
+// mergeRanges 排序并合并重叠或相邻的地址段
+func mergeRanges(ranges []addrRange) []addrRange {
+	if len(ranges) == 0 {
+		return nil
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].from.Less(ranges[j].from) })
+	merged := ranges[:1]
+	for _, r := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		sameFamily := last.to.BitLen() == r.from.BitLen() && last.to.Zone() == r.from.Zone()
+		//带zone的地址只去重不合并，保持为单个地址
+		adjacent := r.from == last.to.Next() && r.from.Zone() == ""
+		if !sameFamily || last.to.Less(r.from) && !adjacent {
+			merged = append(merged, r)
+			continue
+		}
+		if last.to.Less(r.to) {
+			last.to = r.to
+		}
+	}
+	return merged
+}
+
+// count 地址数，超过uint64时为math.MaxUint64
+func (r addrRange) count() uint64 {
+	if r.from.Is4() {
+		from, to := r.from.As4(), r.to.As4()
+		return uint64(binary.BigEndian.Uint32(to[:])-binary.BigEndian.Uint32(from[:])) + 1
+	}
+	from, to := r.from.As16(), r.to.As16()
+	lo, borrow := bits.Sub64(binary.BigEndian.Uint64(to[8:]), binary.BigEndian.Uint64(from[8:]), 0)
+	hi, _ := bits.Sub64(binary.BigEndian.Uint64(to[:8]), binary.BigEndian.Uint64(from[:8]), borrow)
+	if hi != 0 || lo == math.MaxUint64 {
+		return math.MaxUint64
+	}
+	return lo + 1
+}
+
+// prefixes 转换为最少的CIDR
+func (r addrRange) prefixes() []netip.Prefix {
+	var prefixes []netip.Prefix
+	for from := r.from; ; {
+		n := from.BitLen()
+		for n > 0 {
+			prefix := netip.PrefixFrom(from, n-1)
+			if prefix.Masked().Addr() != from || r.to.Less(lastAddr(prefix)) {
+				break
+			}
+			n--
+		}
+		prefix := netip.PrefixFrom(from, n)
+		prefixes = append(prefixes, prefix)
+		last := lastAddr(prefix)
+		if last == r.to {
+			return prefixes
+		}
+		from = last.Next()
+	}
+}
+
+// lastAddr CIDR的最后一个地址
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	addr := prefix.Masked().Addr()
+	if addr.Is4() {
+		a := addr.As4()
+		v := binary.BigEndian.Uint32(a[:]) | uint32(uint64(1)<<(32-prefix.Bits())-1)
+		binary.BigEndian.PutUint32(a[:], v)
+		return netip.AddrFrom4(a)
+	}
+	a := addr.As16()
+	hi, lo := binary.BigEndian.Uint64(a[:8]), binary.BigEndian.Uint64(a[8:])
+	if hostBits := 128 - prefix.Bits(); hostBits >= 64 {
+		hi |= pow2(hostBits-64) - 1
+		if hostBits == 128 {
+			hi = math.MaxUint64
+		}
+		lo = math.MaxUint64
+	} else {
+		lo |= pow2(hostBits) - 1
+	}
+	binary.BigEndian.PutUint64(a[:8], hi)
+	binary.BigEndian.PutUint64(a[8:], lo)
+	return netip.AddrFrom16(a)
+}
+
+// addAddr 地址加n
+func addAddr(addr netip.Addr, n uint64) netip.Addr {
+	if addr.Is4() {
+		a := addr.As4()
+		binary.BigEndian.PutUint32(a[:], binary.BigEndian.Uint32(a[:])+uint32(n))
+		return netip.AddrFrom4(a)
+	}
+	a := addr.As16()
+	lo, carry := bits.Add64(binary.BigEndian.Uint64(a[8:]), n, 0)
+	hi, _ := bits.Add64(binary.BigEndian.Uint64(a[:8]), 0, carry)
+	binary.BigEndian.PutUint64(a[:8], hi)
+	binary.BigEndian.PutUint64(a[8:], lo)
+	return netip.AddrFrom16(a).WithZone(addr.Zone())
+}
+
+// pow2 2的n次方，超过uint64时为math.MaxUint64
+func pow2(n int) uint64 {
+	if n >= 64 {
+		return math.MaxUint64
+	}
+	return 1 << n
+}
+
+func addCount(a, b uint64) uint64 {
+	sum, carry := bits.Add64(a, b, 0)
+	if carry != 0 {
+		return math.MaxUint64
+	}
+	return sum
+}
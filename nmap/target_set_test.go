@@ -0,0 +1,128 @@
+package nmap
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		spec  string
+		kind  TargetKind
+		count uint64
+	}{
+		{"192.168.1.1", TargetIP, 1},
+		{"fe80::a8bb:ccff:fedd:eeff%eth0", TargetIP, 1},
+		{"192.168.10.40/24", TargetCIDR, 256},
+		{"0.0.0.0/0", TargetCIDR, 1 << 32},
+		{"2001:db8::/120", TargetCIDR, 256},
+		{"2001:db8::/32", TargetCIDR, math.MaxUint64},
+		{"10.0-3.1-254.*", TargetRange, 4 * 254 * 256},
+		{"192.168.3-5,7.1", TargetRange, 4},
+		{"0-255.0-255.13.37", TargetRange, 65536},
+		{"10.0.0,1,3-7.-", TargetRange, 7 * 256},
+		{"Scanme.nmap.org", TargetHostname, 1},
+		{"scanme.nmap.org/16", TargetHostname, 65536},
+	}
+	for _, test := range tests {
+		target, err := ParseTarget(test.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if target.Kind != test.kind || target.Count() != test.count {
+			t.Fatalf("%s: expected %s %d, but got %s %d", test.spec, test.kind, test.count, target.Kind, target.Count())
+		}
+	}
+	for _, spec := range []string{"300.1.1.1", "10.0.0", "10.0.0.1/33", "10.0-3.0.0/24", "10.0.5-1.1", "2001:db8::/129", "a b", "-sS", "host/x"} {
+		if _, err := ParseTarget(spec); !errors.Is(err, ErrInvalidTarget) {
+			t.Fatalf("%s: expected ErrInvalidTarget, but got %v", spec, err)
+		}
+	}
+}
+
+func TestTargetSet(t *testing.T) {
+	set, err := NewTargetSet("192.168.1.0/24 192.168.1.10-20", "192.168.2.0", "scanme.nmap.org", "SCANME.nmap.org")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := set.Count(); count != 258 {
+		t.Fatalf("expected 258 targets, but got %d", count)
+	}
+	excludes, err := NewTargetSet("192.168.1.0", "192.168.1.128/25", "192.168.2.0", "scanme.nmap.org")
+	if err != nil {
+		t.Fatal(err)
+	}
+	set = set.Subtract(excludes)
+	want := []string{"192.168.1.1", "192.168.1.2/31", "192.168.1.4/30", "192.168.1.8/29", "192.168.1.16/28", "192.168.1.32/27", "192.168.1.64/26"}
+	if targets := set.Targets(); !reflect.DeepEqual(targets, want) {
+		t.Fatalf("expected %v, but got %v", want, targets)
+	}
+	if expanded := set.Expand(); len(expanded) != 127 || expanded[0] != "192.168.1.1" || expanded[126] != "192.168.1.127" {
+		t.Fatalf("expected 192.168.1.1-127, but got %v", expanded)
+	}
+	if !set.Contains("192.168.1.127") || set.Contains("192.168.1.128") || set.Contains("192.168.1.0") {
+		t.Fatal("unexpected Contains result")
+	}
+	if err := set.Limit(100); !errors.Is(err, ErrTooManyTargets) {
+		t.Fatalf("expected ErrTooManyTargets, but got %v", err)
+	}
+
+	//相邻的带zone地址不合并
+	zoned, err := NewTargetSet("fe80::1%eth0", "fe80::2%eth0", "fe80::1%eth0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if targets := zoned.Targets(); !reflect.DeepEqual(targets, []string{"fe80::1%eth0", "fe80::2%eth0"}) || zoned.Count() != 2 {
+		t.Fatalf("expected fe80::1%%eth0 and fe80::2%%eth0, but got %v", targets)
+	}
+
+	shards := set.Shard(3)
+	var sizes []uint64
+	var expanded []string
+	for _, shard := range shards {
+		sizes = append(sizes, shard.Count())
+		expanded = append(expanded, shard.Expand()...)
+	}
+	if !reflect.DeepEqual(sizes, []uint64{43, 42, 42}) || !reflect.DeepEqual(expanded, set.Expand()) {
+		t.Fatalf("unexpected shards %v", sizes)
+	}
+}
+
+func TestNmapTargetSet(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "targets.txt")
+	exclude := filepath.Join(dir, "exclude.txt")
+	if err := os.WriteFile(input, []byte("# targets\n10.0.0.0/30 10.0.1.1\n10.0.2.1-3 # range\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(exclude, []byte("10.0.2.2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	scanner := NewNmap().AddsS().AddTargets("10.0.0.0/31", "10.0.3.1").AddiL(input).Addexclude("10.0.0.0,10.0.1.1").Addexcludefile(exclude)
+	set, err := scanner.TargetSet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.2.1", "10.0.2.3", "10.0.3.1"}
+	if expanded := set.Expand(); !reflect.DeepEqual(expanded, want) {
+		t.Fatalf("expected %v, but got %v", want, expanded)
+	}
+
+	if _, err := NewNmap().AddiR(10).TargetSet(); !errors.Is(err, ErrInvalidTarget) {
+		t.Fatalf("expected ErrInvalidTarget, but got %v", err)
+	}
+
+	//未知参数的值不作为目标，合并的参数正常展开
+	if _, err := AddArgs(NewNmap(), "--foo", "bar").AddTargets("10.0.0.1").TargetSet(); !errors.Is(err, ErrUnknownOption) {
+		t.Fatalf("expected ErrUnknownOption, but got %v", err)
+	}
+	set, err = AddArgs(NewNmap(), "-np", "80", "--foo", "-sSV").AddTargets("10.0.0.1").TargetSet()
+	if err != nil || !reflect.DeepEqual(set.Targets(), []string{"10.0.0.1"}) {
+		t.Fatalf("expected 10.0.0.1, but got %v, %v", set, err)
+	}
+}
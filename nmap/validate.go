@@ -0,0 +1,264 @@
+package nmap
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ValidationKind 参数问题的类型
+type ValidationKind string
+
+const (
+	//构建参数时的错误，如AddoN("a", "b")
+	ValidationBuild ValidationKind = "build"
+	//互斥的参数，如-sS和-sT，-sL和-O
+	ValidationConflict ValidationKind = "conflict"
+	//超出范围的值，如-T 9、--top-ports 0
+	ValidationRange ValidationKind = "range"
+	//需要root权限，如-sS、-O
+	ValidationRoot ValidationKind = "root"
+	//不支持IPv6（-6），如-PP、--ip-options
+	ValidationIPv6 ValidationKind = "ipv6"
+	//需要同时指定的参数或值，如--version-intensity需要-sV
+	ValidationRequires ValidationKind = "requires"
+)
+
+// ValidationError 一个参数问题
+type ValidationError struct {
+	Kind ValidationKind `json:"kind"`
+	//有问题的参数，如-sL、--top-ports
+	Option  string `json:"option"`
+	Message string `json:"message"`
+}
+
+func (e *ValidationError) Error() string {
+	if e.Option == "" {
+		return e.Message
+	}
+	return e.Option + ": " + e.Message
+}
+
+// ValidationErrors Validate发现的所有问题，errors.Is(err, ErrInvalidOption)为true，可通过errors.As获取
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	if len(e) == 1 {
+		return ErrInvalidOption.Error() + ": " + e[0].Error()
+	}
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("%d %ss: %s", len(e), ErrInvalidOption, strings.Join(msgs, "; "))
+}
+
+func (e ValidationErrors) Is(target error) bool {
+	return target == ErrInvalidOption
+}
+
+// Kind 指定类型的问题
+func (e ValidationErrors) Kind(kind ValidationKind) ValidationErrors {
+	var errs ValidationErrors
+	for _, err := range e {
+		if err.Kind == kind {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+var (
+	//只能指定一种TCP扫描方式
+	tcpScanTypes = []string{"-sS", "-sT", "-sA", "-sW", "-sM", "-sN", "-sF", "-sX", "-sI", "-b"}
+	//只能指定一种SCTP扫描方式
+	sctpScanTypes = []string{"-sY", "-sZ"}
+	//所有端口扫描方式
+	portScanTypes = append(append([]string{"-sU", "-sO"}, tcpScanTypes...), sctpScanTypes...)
+	//-sn不扫描端口，不能与端口扫描和依赖端口的检测同时使用
+	noPortScanConflicts = append([]string{"-O", "-sV"}, portScanTypes...)
+	//-sL只列出目标，不能与任何扫描和检测同时使用
+	listScanConflicts = append([]string{"-sn", "-sC", "--script", "-A", "--traceroute"}, noPortScanConflicts...)
+	//最小值不能大于最大值
+	minMaxOptions = [][2]string{
+		{"--min-hostgroup", "--max-hostgroup"},
+		{"--min-parallelism", "--max-parallelism"},
+		{"--min-rate", "--max-rate"},
+	}
+	timingTemplates = []string{"paranoid", "sneaky", "polite", "normal", "aggressive", "insane"}
+	//时间，如 900、900s、15m、500ms
+	timeRegexp = regexp.MustCompile(`^\d+(\.\d+)?(ms|s|m|h)?$`)
+)
+
+// Validate 检查参数：互斥的扫描方式、超出范围的值、需要root权限的参数、不支持IPv6的参数和需要同时指定的参数，
+// 同时返回构建参数时的错误，没有问题时返回nil，否则返回包含所有问题的ValidationErrors
+//
+// Run时自动检查，只能识别本包中Add*方法对应的参数，其他参数（包括AddArgs添加的未知参数）忽略
+func (receiver *nmap) Validate() error {
+	var errs ValidationErrors
+	for _, err := range receiver.errs {
+		var validationErr *ValidationError
+		if errors.As(err, &validationErr) {
+			errs = append(errs, validationErr)
+		} else {
+			errs = append(errs, &ValidationError{Kind: ValidationBuild, Message: err.Error()})
+		}
+	}
+	add := func(kind ValidationKind, option, format string, a ...any) {
+		errs = append(errs, &ValidationError{Kind: kind, Option: option, Message: fmt.Sprintf(format, a...)})
+	}
+
+	//未知的参数忽略
+	var options []Option
+	present := make(map[string]string)
+	all, _ := parseArgs(receiver.Args)
+	for _, option := range all {
+		if _, ok := optionSpecs[option.Name]; ok {
+			options = append(options, option)
+			present[option.Name] = option.Value
+		}
+	}
+	has := func(names ...string) []string {
+		var found []string
+		for _, name := range names {
+			if _, ok := present[name]; ok {
+				found = append(found, name)
+			}
+		}
+		return found
+	}
+	privileged := isPrivileged(receiver.Args)
+	_, ipv6 := present["-6"]
+	reported := make(map[string]bool)
+	for _, option := range options {
+		spec := optionSpecs[option.Name]
+		if option.missing {
+			add(ValidationRequires, option.Name, "requires a value")
+		} else if spec.check != nil {
+			if msg := spec.check(option.Value); msg != "" {
+				add(ValidationRange, option.Name, "%q %s", option.Value, msg)
+			}
+		}
+		//同一参数的权限、IPv6和依赖问题只报告一次
+		if reported[option.Name] {
+			continue
+		}
+		reported[option.Name] = true
+		if spec.root && !privileged {
+			add(ValidationRoot, option.Name, "requires root privileges")
+		}
+		if spec.noIPv6 && ipv6 {
+			add(ValidationIPv6, option.Name, "not supported with -6")
+		}
+		if len(spec.requires) != 0 && len(has(spec.requires...)) == 0 {
+			add(ValidationRequires, option.Name, "requires %s", strings.Join(spec.requires, " or "))
+		}
+	}
+
+	if found := has(tcpScanTypes...); len(found) > 1 {
+		add(ValidationConflict, found[0], "only one TCP scan type can be used, got %s", strings.Join(found, ", "))
+	}
+	if found := has(sctpScanTypes...); len(found) > 1 {
+		add(ValidationConflict, found[0], "only one SCTP scan type can be used, got %s", strings.Join(found, ", "))
+	}
+	if has("-sL") != nil {
+		if found := has(listScanConflicts...); len(found) != 0 {
+			add(ValidationConflict, "-sL", "list scan cannot be combined with %s", strings.Join(found, ", "))
+		}
+	} else if has("-sn") != nil {
+		if found := has(noPortScanConflicts...); len(found) != 0 {
+			add(ValidationConflict, "-sn", "no port scan cannot be combined with %s", strings.Join(found, ", "))
+		}
+	}
+	if has("-F") != nil && has("-p") != nil {
+		add(ValidationConflict, "-F", "cannot be combined with -p, use --top-ports or --port-ratio instead")
+	}
+	for _, pair := range minMaxOptions {
+		minValue, err1 := strconv.ParseFloat(present[pair[0]], 64)
+		maxValue, err2 := strconv.ParseFloat(present[pair[1]], 64)
+		if err1 == nil && err2 == nil && minValue > maxValue {
+			add(ValidationRange, pair[0], "%s is greater than %s %s", present[pair[0]], pair[1], present[pair[1]])
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// isPrivileged nmap是否以root权限运行，--privileged或NMAP_PRIVILEGED环境变量视为有权限，
+// 无法判断时（如windows）视为有权限
+func isPrivileged(args []string) bool {
+	if hasArg(args, "--unprivileged") {
+		return false
+	}
+	if hasArg(args, "--privileged") || os.Getenv("NMAP_PRIVILEGED") != "" {
+		return true
+	}
+	uid := os.Geteuid()
+	return uid == 0 || uid == -1
+}
+
+// intRange 整数在[min, max]范围内，max为-1时没有上限
+func intRange(min, max int) func(value string) string {
+	return func(value string) string {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return "is not an integer"
+		}
+		if n < min || max != -1 && n > max {
+			if max == -1 {
+				return fmt.Sprintf("must be %d or greater", min)
+			}
+			return fmt.Sprintf("must be between %d and %d", min, max)
+		}
+		return ""
+	}
+}
+
+func ratio(value string) string {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f < 0 || f > 1 {
+		return "must be between 0.0 and 1.0"
+	}
+	return ""
+}
+
+func positive(value string) string {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f <= 0 {
+		return "must be greater than 0"
+	}
+	return ""
+}
+
+func mtu(value string) string {
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 || n%8 != 0 {
+		return "must be a multiple of 8"
+	}
+	return ""
+}
+
+// timing -T的值为0-5或模板名称
+func timing(value string) string {
+	if contains(timingTemplates, value) {
+		return ""
+	}
+	if n, err := strconv.Atoi(value); err == nil && n >= 0 && n <= 5 {
+		return ""
+	}
+	return "must be 0-5 or " + strings.Join(timingTemplates, "|")
+}
+
+// timeValue 时间，默认单位为秒，可加ms、s、m、h
+func timeValue(value string) string {
+	if timeRegexp.MatchString(value) {
+		return ""
+	}
+	return "is not a valid time, such as 500ms, 30s, 15m, 1h"
+}
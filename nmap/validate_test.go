@@ -0,0 +1,58 @@
+package nmap
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		name string
+		nmap *nmap
+		//问题的类型和参数
+		want []string
+	}{
+		{"valid", AddArgs(NewNmap().AddsS().AddsV().Addversionintensity(5).AddT(4).Addp("80,443").AddTargets("scanme.nmap.org"), "--privileged"), nil},
+		{"list scan", AddArgs(NewNmap().AddsL().AddsS().AddO(), "--privileged"), []string{"conflict -sL"}},
+		{"tcp scan types", AddArgs(NewNmap().AddsS().AddsT(), "--privileged"), []string{"conflict -sS"}},
+		{"no port scan", AddArgs(NewNmap().Addsn().AddsU(), "--privileged"), []string{"conflict -sn"}},
+		{"fast", NewNmap().AddF().Addp("80"), []string{"conflict -F"}},
+		{"range", NewNmap().AddT(9).Addversionintensity(42).Addtopports(-1), []string{"range --version-intensity", "range --top-ports", "range -T"}},
+		{"attached", AddArgs(NewNmap(), "-T9", "--max-rate=0", "--mtu", "10", "--privileged"), []string{"range -T", "range --max-rate", "range --mtu"}},
+		{"min max", NewNmap().Addminhostgroup(256).Addmaxhostgroup(64), []string{"range --min-hostgroup"}},
+		{"root", AddArgs(NewNmap().AddsS().AddO(), "--unprivileged"), []string{"root -sS", "root -O"}},
+		//nmap没有root权限时改用TCP ping，忽略-f、-D，不报告
+		{"unprivileged fallback", AddArgs(NewNmap().AddsT().AddPE().AddPP().AddPU("53").AddPO(), "-f", "-D", "RND:3", "--unprivileged"), nil},
+		{"ipv6", AddArgs(NewNmap().Add6().AddPP().AddiR(10), "--privileged"), []string{"ipv6 -PP", "ipv6 -iR"}},
+		{"requires", NewNmap().Addversionintensity(3).Addosscanguess().Addscriptargs("user=foo"), []string{"requires --version-intensity", "requires --osscan-guess", "requires --script-args"}},
+		{"missing value", AddArgs(NewNmap(), "-p"), []string{"requires -p"}},
+		{"build", NewNmap().AddoN("a", "b"), []string{"build "}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.nmap.Validate()
+			if c.want == nil {
+				if err != nil {
+					t.Errorf("expected no error, but got %v", err)
+				}
+				return
+			}
+			var errs ValidationErrors
+			if !errors.As(err, &errs) || !errors.Is(err, ErrInvalidOption) {
+				t.Fatalf("expected ValidationErrors, but got %v", err)
+			}
+			var got []string
+			for _, e := range errs {
+				got = append(got, string(e.Kind)+" "+e.Option)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("expected %q, but got %q: %v", c.want, got, err)
+			}
+			if _, err := c.nmap.Run(); !errors.Is(err, ErrInvalidOption) {
+				t.Errorf("expected Run to validate, but got %v", err)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package nmap
 
 import (
 	"encoding/xml"
+	"strconv"
 )
 
 // nmap xml result => struct
@@ -102,7 +103,7 @@ type Smurf struct {
 }
 type ExtraReasons struct {
 	Reason string       `json:"reason" xml:"reason,attr"`
-	Count  string       `json:"count" xml:"count,attr"`
+	Count  int          `json:"count" xml:"count,attr"`
 	Proto  PortProtocol `json:"proto" xml:"proto,attr"`
 	Ports  string       `json:"ports" xml:"ports,attr"`
 }
@@ -141,12 +142,14 @@ type Service struct {
 }
 type Elem struct {
 	Key  string `json:"key,omitempty" xml:"key,attr,omitempty"`
-	Text string `json:"text" xml:",innerxml"`
+	Text string `json:"text" xml:",chardata"`
 }
+
+// Table NSE结构化输出，table可以任意嵌套
 type Table struct {
-	Key   string `json:"key,omitempty" xml:"key,attr,omitempty"`
-	Table []Elem `json:"table,omitempty" xml:"table,omitempty"`
-	Elem  []Elem `json:"elem,omitempty" xml:"elem,omitempty"`
+	Key   string  `json:"key,omitempty" xml:"key,attr,omitempty"`
+	Table []Table `json:"table,omitempty" xml:"table,omitempty"`
+	Elem  []Elem  `json:"elem,omitempty" xml:"elem,omitempty"`
 }
 type Script struct {
 	Id     string `json:"id" xml:"id,attr"`
@@ -176,7 +179,7 @@ type OSClass struct {
 	Vendor   string   `json:"vendor" xml:"vendor,attr"`
 	OSGen    string   `json:"osgen" xml:"osgen,attr"`
 	Type     string   `json:"type" xml:"type,attr"`
-	Accuracy string   `json:"accuracy" xml:"accuracy,attr"`
+	Accuracy int      `json:"accuracy" xml:"accuracy,attr"`
 	OSFamily string   `json:"osfamily" xml:"osfamily,attr"`
 	CPE      []string `json:"cpe" xml:"cpe"`
 }
@@ -207,26 +210,66 @@ type TCPSequence struct {
 	Values     string `json:"values" xml:"values,attr"`
 }
 type IpIdSequence struct {
+	Class  string `json:"class" xml:"class,attr"`
 	Values string `json:"values" xml:"values,attr"`
 }
 type TCPTSSequence struct {
+	Class  string `json:"class" xml:"class,attr"`
 	Values string `json:"values" xml:"values,attr"`
 }
 type Hop struct {
 	TTL    int    `json:"ttl" xml:"ttl,attr"`
-	RTT    string `json:"rtt" xml:"rtt,attr"`
+	RTT    RTT    `json:"rtt" xml:"rtt,attr"`
 	Ipaddr string `json:"ipaddr" xml:"ipaddr,attr"`
-	Host   string `json:"host" xml:"host,attr"`
+	Host   string `json:"host" xml:"host,attr,omitempty"`
+}
+
+// RTT 毫秒，nmap对未测得的hop输出--，解析为RTTUnknown
+type RTT float64
+
+// RTTUnknown nmap输出--的RTT，序列化时仍为--
+const RTTUnknown RTT = -1
+
+// Known 是否为nmap测得的RTT
+func (r RTT) Known() bool {
+	return r >= 0
 }
+
+func (r *RTT) UnmarshalXMLAttr(attr xml.Attr) error {
+	switch attr.Value {
+	case "":
+		*r = 0
+		return nil
+	case "--":
+		*r = RTTUnknown
+		return nil
+	}
+	v, err := strconv.ParseFloat(attr.Value, 64)
+	if err != nil {
+		return err
+	}
+	*r = RTT(v)
+	return nil
+}
+
+func (r RTT) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	if !r.Known() {
+		return xml.Attr{Name: name, Value: "--"}, nil
+	}
+	return xml.Attr{Name: name, Value: strconv.FormatFloat(float64(r), 'f', -1, 64)}, nil
+}
+
 type Trace struct {
-	Proto string `json:"proto" xml:"proto,attr"`
-	Port  string `json:"port" xml:"port,attr"`
+	Proto string `json:"proto" xml:"proto,attr,omitempty"`
+	Port  int    `json:"port" xml:"port,attr,omitempty"`
 	Hop   []Hop  `json:"hop" xml:"hop"`
 }
+
+// Times 微秒
 type Times struct {
-	SRTT   string `json:"srtt" xml:"srtt,attr"`
-	RTTVar string `json:"rttvar" xml:"rttvar,attr"`
-	To     string `json:"to" xml:"to,attr"`
+	SRTT   int `json:"srtt" xml:"srtt,attr"`
+	RTTVar int `json:"rttvar" xml:"rttvar,attr"`
+	To     int `json:"to" xml:"to,attr"`
 }
 type Host struct {
 	StartTime int64      `json:"starttime" xml:"starttime,attr,omitempty"`
@@ -245,7 +288,7 @@ type Host struct {
 	TCPSequence   []TCPSequence   `json:"tcpsequence" xml:"tcpsequence"`
 	IpIdSequence  []IpIdSequence  `json:"ipidsequence" xml:"ipidsequence"`
 	TCPTSSequence []TCPTSSequence `json:"tcptssequence" xml:"tcptssequence"`
-	HostScript    []Script        `json:"hostscript" xml:"hostscript"`
+	HostScript    []Script        `json:"hostscript" xml:"hostscript>script"`
 	Trace         []Trace         `json:"trace" xml:"trace"`
 	Times         Times           `json:"times" xml:"times"`
 }
@@ -259,14 +302,15 @@ type Hostname struct {
 	Type HostnameType `json:"type" xml:"type,attr"`
 }
 type HostHint struct {
-	Status    Status     `json:"status" xml:"status"`
-	Address   Address    `json:"address" xml:"address"`
+	Status Status `json:"status" xml:"status"`
+	//ip地址，局域网扫描时还包括mac地址
+	Address   []Address  `json:"address" xml:"address"`
 	Hostnames []Hostname `json:"hostnames" xml:"hostnames>hostname"`
 }
 
 type Output struct {
 	Type string `json:"type" xml:"type,attr"`
-	Text string `json:"text" xml:"text,attr"`
+	Text string `json:"text" xml:",chardata"`
 }
 type Hosts struct {
 	Up    int `json:"up" xml:"up,attr"`
@@ -285,9 +329,11 @@ type Finished struct {
 type RunStats struct {
 	Finished Finished `json:"finished" xml:"finished"`
 	Hosts    Hosts    `json:"hosts" xml:"hosts"`
+	//xml不完整，由已解析的host生成，不是nmap输出的
+	Incomplete bool `json:"incomplete,omitempty" xml:"-"`
 }
 type NmapXMLResult struct {
-	XMLName xml.Name `json:"nmaprun" xml:"nmaprun"`
+	XMLName xml.Name `json:"-" xml:"nmaprun"`
 	//nmap默认为nmap
 	Scanner string `json:"scanner" xml:"scanner,attr"`
 	//参数
@@ -312,7 +358,7 @@ type NmapXMLResult struct {
 	//扫描目标的状态、ip、hostname
 	HostHint   []HostHint `json:"hosthint" xml:"hosthint"`
 	Prescript  []Script   `json:"prescript" xml:"prescript>script"`
-	Postscript []Script   `json:"postscript" xml:"postscript>scriptd"`
-	Output     Output     `json:"output" xml:"output"`
+	Postscript []Script   `json:"postscript" xml:"postscript>script"`
+	Output     []Output   `json:"output" xml:"output"`
 	RunStats   RunStats   `json:"runstats" xml:"runstats"`
 }
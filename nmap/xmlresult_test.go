@@ -0,0 +1,135 @@
+package nmap
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// xmlCorpus 真实的nmap xml结果
+func xmlCorpus(t *testing.T) []string {
+	files, err := filepath.Glob("testdata/*.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(files, "../examples/parsewithfile/nmap_example.xml")
+}
+
+func loadXML(t *testing.T, fileName string) *NmapXMLResult {
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := ParseXML(content)
+	if err != nil {
+		t.Fatalf("%s: %v", fileName, err)
+	}
+	return result
+}
+
+// unmarshal => marshal => unmarshal 结果一致
+func TestXMLRoundTrip(t *testing.T) {
+	for _, fileName := range xmlCorpus(t) {
+		t.Run(filepath.Base(fileName), func(t *testing.T) {
+			first := loadXML(t, fileName)
+			content, err := xml.Marshal(first)
+			if err != nil {
+				t.Fatal(err)
+			}
+			second, err := ParseXML(content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(first, second) {
+				t.Errorf("round trip result differs")
+			}
+		})
+	}
+}
+
+func TestXMLModel(t *testing.T) {
+	result := loadXML(t, "testdata/scanme.xml")
+	if len(result.Prescript) != 1 || len(result.Postscript) != 1 {
+		t.Errorf("expected prescript and postscript, but got %d %d", len(result.Prescript), len(result.Postscript))
+	}
+	if len(result.Output) != 1 || result.Output[0].Text == "" {
+		t.Errorf("expected interactive output, but got %v", result.Output)
+	}
+	if len(result.HostHint) != 1 || len(result.HostHint[0].Address) != 2 {
+		t.Errorf("expected hosthint with ip and mac address, but got %v", result.HostHint)
+	}
+
+	host := result.Host[0]
+	if host.Status.ReasonTTl != 53 || host.Times.SRTT != 154322 || host.Trace[0].Port != 80 || host.Trace[0].Hop[2].RTT != 154.33 {
+		t.Errorf("unexpected host values %+v %+v %+v", host.Status, host.Times, host.Trace)
+	}
+	if host.OS[0].OSMatch[0].OSClass[0].Accuracy != 95 || host.IpIdSequence[0].Class != "All zeros" {
+		t.Errorf("unexpected os values %+v", host.OS[0].OSMatch[0])
+	}
+	if len(host.HostScript) != 1 || host.HostScript[0].Id != "dns-nsid" {
+		t.Errorf("expected dns-nsid hostscript, but got %v", host.HostScript)
+	}
+	//ssl-enum-ciphers: TLSv1.2 => ciphers => [cipher]
+	script := host.Ports[0].Port[2].Script[1]
+	if cipher := script.Table[0].Table[0].Table[0]; len(cipher.Elem) != 3 || cipher.Elem[1].Text != "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256" {
+		t.Errorf("unexpected nested table %+v", cipher)
+	}
+	if state := result.Host[1].Ports[0].Port[3].State.State; state != PortState(OpenFiltered) {
+		t.Errorf("expected %s, but got %s", OpenFiltered, state)
+	}
+}
+
+func TestXMLCorpusModel(t *testing.T) {
+	//-sn：hosthint和down的host
+	result := loadXML(t, "testdata/pingsweep.xml")
+	if len(result.HostHint) != 2 || result.HostHint[1].Address[1].Vendor != "Oracle VirtualBox virtual NIC" || len(result.Host) != 8 {
+		t.Errorf("unexpected ping scan %+v", result.HostHint)
+	}
+	if up := result.Hosts().Up(); len(up) != 3 || up[1].Hostnames[0].Name != "dhcp.vbox.lan" {
+		t.Errorf("expected 3 up hosts, but got %d", len(up))
+	}
+
+	//-A：ssl-cert的pubkey => ecdhparams => curve_params
+	result = loadXML(t, "testdata/aggressive.xml")
+	host := result.Host[0]
+	cert := host.FindPort(PortProtocolTcp, 443).FindScript("ssl-cert")
+	if cert == nil || cert.Table[2].Key != "pubkey" || cert.Table[2].Table[0].Table[0].Elem[1].Text != "prime256v1" ||
+		cert.Table[3].Table[1].Elem[1].Text != "DNS:www.example.org, DNS:example.org" {
+		t.Errorf("unexpected ssl-cert %+v", cert)
+	}
+	if host.Uptime[0].Seconds != 1209731 || host.Distance[0].Value != 11 || host.Trace[0].Hop[2].Host != "www.example.org" {
+		t.Errorf("unexpected host values %+v %+v %+v", host.Uptime, host.Distance, host.Trace)
+	}
+
+	//-sU --traceroute：多个extraports，udp的trace
+	result = loadXML(t, "testdata/udp-traceroute.xml")
+	host = result.Host[0]
+	if extraPorts := host.Ports[0].ExtraPorts; len(extraPorts) != 2 || extraPorts[1].State != PortState(OpenFiltered) || extraPorts[0].ExtraReasons[0].Proto != PortProtocolUdpProto {
+		t.Errorf("unexpected extraports %+v", extraPorts)
+	}
+	if trace := host.Trace[0]; trace.Proto != "udp" || trace.Port != 53 || len(trace.Hop) != 4 || trace.Hop[2].TTL != 4 {
+		t.Errorf("unexpected trace %+v", trace)
+	}
+}
+
+func TestRTT(t *testing.T) {
+	//0是测得的值，只有--序列化为--
+	for _, value := range []string{"0", "--", "154.33"} {
+		var hop Hop
+		if err := xml.Unmarshal([]byte(`<hop ttl="1" rtt="`+value+`" ipaddr="10.0.0.1"/>`), &hop); err != nil {
+			t.Fatal(err)
+		}
+		if hop.RTT.Known() != (value != "--") {
+			t.Errorf("%s: unexpected Known %v", value, hop.RTT.Known())
+		}
+		content, err := xml.Marshal(hop)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := `<Hop ttl="1" rtt="` + value + `" ipaddr="10.0.0.1"></Hop>`; string(content) != want {
+			t.Errorf("expected %s, but got %s", want, content)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package nmap
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// StreamEventType 流式解析时产生的事件类型，与nmap xml中的元素名一致
+type StreamEventType string
+
+const (
+	EventHost         StreamEventType = "host"
+	EventHostHint     StreamEventType = "hosthint"
+	EventTaskBegin    StreamEventType = "taskbegin"
+	EventTaskProgress StreamEventType = "taskprogress"
+	EventTaskEnd      StreamEventType = "taskend"
+	EventRunStats     StreamEventType = "runstats"
+)
+
+// StreamEvent nmap运行过程中每个闭合的元素，Type对应的字段不为nil
+type StreamEvent struct {
+	Type         StreamEventType
+	Host         *Host
+	HostHint     *HostHint
+	TaskBegin    *TaskBegin
+	TaskProgress *TaskProgress
+	TaskEnd      *TaskEnd
+	RunStats     *RunStats
+}
+
+// StreamHandler 处理流式事件，在解析xml的goroutine中同步调用，处理过慢会阻塞nmap的输出
+type StreamHandler func(event *StreamEvent)
+
+// decodeXMLStream 逐个元素解析nmap的xml输出，解析结果累加到result，
+// host/hosthint/taskbegin/taskprogress/taskend/runstats元素闭合后立即回调handler
+func decodeXMLStream(r io.Reader, result *NmapXMLResult, handler StreamHandler) error {
+	return newStreamDecoder(r, result, handler).decode()
+}
+
+// streamDecoder 逐个元素解析nmap的xml输出，并记录解析到的位置，用于恢复不完整的xml
+type streamDecoder struct {
+	decoder *xml.Decoder
+	result  *NmapXMLResult
+	handler StreamHandler
+	//是否读取到nmaprun
+	root bool
+	//是否读取到</nmaprun>
+	closed bool
+	//是否读取到runstats
+	runStats bool
+	//最后一个完整元素结束的位置
+	offset int64
+	//解析出错时正在解析的顶层元素
+	element string
+}
+
+func newStreamDecoder(r io.Reader, result *NmapXMLResult, handler StreamHandler) *streamDecoder {
+	return &streamDecoder{
+		decoder: xml.NewDecoder(r),
+		result:  result,
+		handler: handler,
+	}
+}
+
+func (d *streamDecoder) emit(event *StreamEvent) {
+	if d.handler != nil {
+		d.handler(event)
+	}
+}
+
+func (d *streamDecoder) decode() error {
+	decoder, result := d.decoder, d.result
+	for {
+		d.offset = decoder.InputOffset()
+		token, err := decoder.Token()
+		if err == io.EOF {
+			if !d.root {
+				return errors.New("no nmaprun element found in xml output")
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if end, ok := token.(xml.EndElement); ok && end.Name.Local == "nmaprun" {
+			d.closed = true
+			continue
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		d.element = start.Name.Local
+		switch start.Name.Local {
+		case "nmaprun":
+			d.root = true
+			result.XMLName = start.Name
+			setNmapRunAttr(result, start.Attr)
+			continue
+		case "scaninfo":
+			var scanInfo ScanInfo
+			err = decoder.DecodeElement(&scanInfo, &start)
+			result.ScanInfo = append(result.ScanInfo, scanInfo)
+		case "verbose":
+			err = decoder.DecodeElement(&result.Verbose, &start)
+		case "debugging":
+			err = decoder.DecodeElement(&result.Debugging, &start)
+		case "target":
+			var target Target
+			err = decoder.DecodeElement(&target, &start)
+			result.Target = append(result.Target, target)
+		case "taskbegin":
+			var taskBegin TaskBegin
+			if err = decoder.DecodeElement(&taskBegin, &start); err == nil {
+				result.TaskBegin = append(result.TaskBegin, taskBegin)
+				d.emit(&StreamEvent{Type: EventTaskBegin, TaskBegin: &taskBegin})
+			}
+		case "taskprogress":
+			var taskProgress TaskProgress
+			if err = decoder.DecodeElement(&taskProgress, &start); err == nil {
+				result.TaskProgress = append(result.TaskProgress, taskProgress)
+				d.emit(&StreamEvent{Type: EventTaskProgress, TaskProgress: &taskProgress})
+			}
+		case "taskend":
+			var taskEnd TaskEnd
+			if err = decoder.DecodeElement(&taskEnd, &start); err == nil {
+				result.TaskEnd = append(result.TaskEnd, taskEnd)
+				d.emit(&StreamEvent{Type: EventTaskEnd, TaskEnd: &taskEnd})
+			}
+		case "hosthint":
+			var hostHint HostHint
+			if err = decoder.DecodeElement(&hostHint, &start); err == nil {
+				result.HostHint = append(result.HostHint, hostHint)
+				d.emit(&StreamEvent{Type: EventHostHint, HostHint: &hostHint})
+			}
+		case "host":
+			var host Host
+			if err = decoder.DecodeElement(&host, &start); err == nil {
+				result.Host = append(result.Host, host)
+				d.emit(&StreamEvent{Type: EventHost, Host: &host})
+			}
+		case "prescript":
+			var scripts scriptList
+			if err = decoder.DecodeElement(&scripts, &start); err == nil {
+				result.Prescript = append(result.Prescript, scripts.Script...)
+			}
+		case "postscript":
+			var scripts scriptList
+			if err = decoder.DecodeElement(&scripts, &start); err == nil {
+				result.Postscript = append(result.Postscript, scripts.Script...)
+			}
+		case "output":
+			var output Output
+			err = decoder.DecodeElement(&output, &start)
+			result.Output = append(result.Output, output)
+		case "runstats":
+			if err = decoder.DecodeElement(&result.RunStats, &start); err == nil {
+				d.runStats = true
+				runStats := result.RunStats
+				d.emit(&StreamEvent{Type: EventRunStats, RunStats: &runStats})
+			}
+		default:
+			//未知元素整体跳过，避免其子元素被误认为顶层元素
+			err = decoder.Skip()
+		}
+		if err != nil {
+			return err
+		}
+		d.element = ""
+	}
+}
+
+// scriptList prescript/postscript中的script列表
+type scriptList struct {
+	Script []Script `xml:"script"`
+}
+
+func setNmapRunAttr(result *NmapXMLResult, attrs []xml.Attr) {
+	for _, attr := range attrs {
+		switch attr.Name.Local {
+		case "scanner":
+			result.Scanner = attr.Value
+		case "args":
+			result.Args = attr.Value
+		case "start":
+			result.Start, _ = strconv.ParseInt(attr.Value, 10, 64)
+		case "startstr":
+			result.StartStr = attr.Value
+		case "version":
+			result.Version = attr.Value
+		case "profile_name":
+			result.ProfileName = attr.Value
+		case "xmloutputversion":
+			result.XMLOutputVersion = attr.Value
+		}
+	}
+}